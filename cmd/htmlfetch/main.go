@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/naozine/nz-html-fetch/pkg/htmlfetch"
+	"go.yaml.in/yaml/v3"
 )
 
 func main() {
@@ -19,6 +23,10 @@ func main() {
 	selector := flag.String("selector", "", "待機するCSSセレクタ")
 	selectorTimeout := flag.Int("selector-timeout", 30, "セレクタ待機タイムアウト（秒）")
 	viewport := flag.String("viewport", "1920x1080", "ビューポートサイズ (WxH)")
+	device := flag.String("device", "", "デバイスエミュレーション (iphone/pixel/ipad/desktop-hidpi)")
+	timezone := flag.String("timezone", "", "タイムゾーン (例: Asia/Tokyo)")
+	locale := flag.String("locale", "", "ロケール (例: ja-JP)")
+	geo := flag.String("geo", "", "位置情報 (緯度,経度[,精度m])")
 	proxy := flag.String("proxy", "", "プロキシアドレス")
 	stealth := flag.Bool("stealth", true, "bot検出回避を有効化")
 	ignoreCertErrors := flag.Bool("ignore-cert-errors", false, "TLS証明書エラーを無視")
@@ -26,10 +34,44 @@ func main() {
 	blockImages := flag.Bool("block-images", false, "画像ブロック")
 	blockCSS := flag.Bool("block-css", false, "CSSブロック")
 	blockFonts := flag.Bool("block-fonts", false, "フォントブロック")
+	cosmetic := flag.Bool("cosmetic", false, "広告枠・Cookieバナー等の要素を削除（-filter-listの##フィルタも適用）")
+	consent := flag.String("consent", "", "Cookie同意ダイアログの操作 (accept/reject)")
+	actionsFile := flag.String("actions", "", "ページ読み込み後に実行する操作（クリック・入力等）のJSON/YAMLファイル")
+	autoScroll := flag.Bool("auto-scroll", false, "無限スクロール・遅延読み込みの画像を展開してから取得")
+	maxScrolls := flag.Int("max-scrolls", 0, "-auto-scrollでページ末尾までスクロールする最大回数（0はデフォルトの20回）")
 	embedCSS := flag.Bool("embed-css", false, "外部CSSを埋め込み")
 	stripScripts := flag.Bool("strip-scripts", false, "スクリプト除去")
+	inlineAssets := flag.Bool("inline-assets", false, "画像・フォント・CSS・iframeをdata URIとして埋め込み")
+	inlineMaxSize := flag.Int64("inline-max-size", 0, "埋め込むリソース1件あたりの上限（バイト、0はデフォルトの5MB）")
 	markdown := flag.Bool("markdown", false, "マークダウン変換を有効化")
-	output := flag.String("output", "html", "出力形式 (html/json/stats/markdown)")
+	output := flag.String("output", "html", "出力形式 (html/json/stats/markdown/pdf/mhtml)")
+	outFile := flag.String("o", "", "出力先ファイル（省略時は標準出力）")
+	warcFile := flag.String("warc", "", "リクエスト・レスポンスを保存するWARCファイル（.warc.gz）")
+	harFile := flag.String("har", "", "全リクエストを記録するHARファイル")
+	capture := flag.String("capture", "", "ボディを記録するレスポンスのURLパターン（例: \"*/api/*\"、-output=jsonで出力）")
+	console := flag.Bool("console", false, "コンソール出力・JSエラーを記録（-output=json/statsで出力、bot検出されやすくなる）")
+	pdfPaper := flag.String("pdf-paper", "a4", "PDFの用紙サイズ (a4/a3/letter/legal)")
+	pdfLandscape := flag.Bool("pdf-landscape", false, "PDFを横向きで出力")
+	pdfBackground := flag.Bool("pdf-background", false, "PDFに背景色・背景画像を含める")
+	pdfMargin := flag.String("pdf-margin", "", "PDFの余白（インチ、\"全体\"または\"上,右,下,左\"形式）")
+	pdfHeader := flag.String("pdf-header", "", "PDFのヘッダーのHTMLテンプレート（pageNumber・totalPages等のクラスに値が入る）")
+	pdfFooter := flag.String("pdf-footer", "", "PDFのフッターのHTMLテンプレート（pageNumber・totalPages等のクラスに値が入る）")
+	screenshot := flag.String("screenshot", "", "スクリーンショットの保存先（拡張子 .png/.jpg/.webp で形式を判定）")
+	screenshotFull := flag.Bool("screenshot-full", false, "ページ全体のスクリーンショットを撮影")
+	screenshotSelector := flag.String("screenshot-selector", "", "指定した要素のみのスクリーンショットを撮影")
+	screenshotQuality := flag.Int("screenshot-quality", 0, "JPEG/WebPの品質 (0-100)")
+	sessionFile := flag.String("session-file", "", "セッション（Cookie・localStorage）を読み込み・保存するJSONファイル")
+	userAgent := flag.String("user-agent", "", "User-Agent（省略時はブラウザのUA）")
+	lang := flag.String("lang", "ja", "Accept-Language")
+	var headers headerFlags
+	flag.Var(&headers, "H", "追加のリクエストヘッダー（\"Name: value\"形式、複数指定可）")
+	var blockURLs, allowURLs stringsFlag
+	flag.Var(&blockURLs, "block-url", "ブロックするURLのパターン（例: \"*/tracking/*\"、複数指定可）")
+	flag.Var(&allowURLs, "allow-url", "ブロックせずに許可するURLのパターン（他のブロック設定より優先、複数指定可）")
+	var hideSelectors stringsFlag
+	flag.Var(&hideSelectors, "hide", "削除する要素のCSSセレクタ（-cosmeticを有効化、複数指定可）")
+	var filterLists stringsFlag
+	flag.Var(&filterLists, "filter-list", "Adblock Plus形式のフィルタリストのファイル（EasyList等、複数指定可）")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "使用法: %s [オプション] URL\n\n", os.Args[0])
@@ -39,6 +81,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s https://example.com\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -block-ads -output=stats https://example.com\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -wait=networkidle -selector=\"#content\" https://example.com\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -session-file=session.json https://example.com/mypage\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -lang=en -H \"Authorization: Bearer TOKEN\" https://example.com/api\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -63,11 +107,45 @@ func main() {
 	if *proxy != "" {
 		fetcherOpts = append(fetcherOpts, htmlfetch.WithProxy(*proxy))
 	}
+	if len(filterLists) > 0 {
+		fetcherOpts = append(fetcherOpts, htmlfetch.WithFilterLists(filterLists...))
+	}
 
 	// Fetchオプションを構築
 	var fetchOpts []htmlfetch.FetchOption
 	fetchOpts = append(fetchOpts, htmlfetch.WithWaitStrategy(parseWaitStrategy(*wait)))
 	fetchOpts = append(fetchOpts, htmlfetch.WithViewport(vpWidth, vpHeight))
+	if *device != "" {
+		d, ok := htmlfetch.LookupDevice(*device)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "エラー: 不明なデバイス: %s\n", *device)
+			os.Exit(1)
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithDevice(d))
+	}
+
+	if *timezone != "" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithTimezone(*timezone))
+	}
+	if *locale != "" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithLocale(*locale))
+	}
+	if *geo != "" {
+		lat, lon, acc, err := parseGeolocation(*geo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+			os.Exit(1)
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithGeolocation(lat, lon, acc))
+	}
+
+	fetchOpts = append(fetchOpts, htmlfetch.WithAcceptLanguage(*lang))
+	if *userAgent != "" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithUserAgent(*userAgent))
+	}
+	if len(headers) > 0 {
+		fetchOpts = append(fetchOpts, htmlfetch.WithHeaders(headers))
+	}
 
 	if *selector != "" {
 		fetchOpts = append(fetchOpts, htmlfetch.WithSelector(*selector, time.Duration(*selectorTimeout)*time.Second))
@@ -81,41 +159,289 @@ func main() {
 		Font:       *blockFonts,
 	}
 	fetchOpts = append(fetchOpts, htmlfetch.WithBlocking(blocking))
+	var blockRules []htmlfetch.BlockRule
+	for _, p := range blockURLs {
+		blockRules = append(blockRules, htmlfetch.BlockRule{Name: p, URLGlob: p})
+	}
+	for _, p := range allowURLs {
+		blockRules = append(blockRules, htmlfetch.BlockRule{URLGlob: p, Allow: true})
+	}
+	if len(blockRules) > 0 {
+		fetchOpts = append(fetchOpts, htmlfetch.WithBlockRules(blockRules...))
+	}
+	switch *consent {
+	case "":
+	case "accept":
+		fetchOpts = append(fetchOpts, htmlfetch.WithConsentHandling(htmlfetch.ConsentAccept))
+	case "reject":
+		fetchOpts = append(fetchOpts, htmlfetch.WithConsentHandling(htmlfetch.ConsentReject))
+	default:
+		fmt.Fprintf(os.Stderr, "エラー: -consentにはacceptまたはrejectを指定してください: %s\n", *consent)
+		os.Exit(1)
+	}
+	if *actionsFile != "" {
+		actions, err := loadActions(*actionsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+			os.Exit(1)
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithActions(actions...))
+	}
+	if *autoScroll {
+		fetchOpts = append(fetchOpts, htmlfetch.WithAutoScroll(htmlfetch.AutoScrollOptions{MaxScrolls: *maxScrolls}))
+	}
+	if *cosmetic || len(hideSelectors) > 0 {
+		var rules []htmlfetch.CosmeticRule
+		for _, s := range hideSelectors {
+			rules = append(rules, htmlfetch.CosmeticRule{Selector: s})
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithCosmeticFiltering(rules...))
+	}
 
 	if *embedCSS {
 		fetchOpts = append(fetchOpts, htmlfetch.WithEmbedCSS())
 	}
+	if *inlineAssets {
+		fetchOpts = append(fetchOpts, htmlfetch.WithInlineAssets())
+		if *inlineMaxSize > 0 {
+			fetchOpts = append(fetchOpts, htmlfetch.WithInlineAssetLimits(*inlineMaxSize, 0))
+		}
+	}
 	if *stripScripts {
 		fetchOpts = append(fetchOpts, htmlfetch.WithStripScripts())
 	}
 	if *markdown || *output == "markdown" {
 		fetchOpts = append(fetchOpts, htmlfetch.WithMarkdown())
 	}
+	if *output == "mhtml" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithMHTML())
+	}
+	if *console {
+		fetchOpts = append(fetchOpts, htmlfetch.WithConsoleCapture())
+	}
+	if *output == "pdf" {
+		paper, ok := pdfPapers[strings.ToLower(*pdfPaper)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "エラー: 不明な用紙サイズ: %s\n", *pdfPaper)
+			os.Exit(1)
+		}
+		var margin *htmlfetch.PDFMargin
+		if *pdfMargin != "" {
+			m, err := parsePDFMargin(*pdfMargin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+				os.Exit(1)
+			}
+			margin = &m
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithPDF(htmlfetch.PDFOptions{
+			Paper:           paper,
+			Margin:          margin,
+			Landscape:       *pdfLandscape,
+			PrintBackground: *pdfBackground,
+			HeaderTemplate:  *pdfHeader,
+			FooterTemplate:  *pdfFooter,
+		}))
+	}
+	if *screenshot != "" {
+		format, err := screenshotFormat(*screenshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
+			os.Exit(1)
+		}
+		fetchOpts = append(fetchOpts, htmlfetch.WithScreenshot(htmlfetch.ScreenshotOptions{
+			Format:   format,
+			Quality:  *screenshotQuality,
+			FullPage: *screenshotFull,
+			Selector: *screenshotSelector,
+		}))
+	}
+
+	if *warcFile != "" {
+		f, err := os.Create(*warcFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: WARCファイルの作成に失敗しました: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		fetchOpts = append(fetchOpts, htmlfetch.WithWARC(f))
+	}
+
+	if *harFile != "" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithHAR())
+	}
+
+	if *capture != "" {
+		fetchOpts = append(fetchOpts, htmlfetch.WithCaptureResponses(htmlfetch.ResponseFilter{URLGlob: *capture}))
+	}
 
 	// フェッチ実行
 	fetcher := htmlfetch.New(fetcherOpts...)
-	result, err := fetcher.Fetch(context.Background(), url, fetchOpts...)
+	var result *htmlfetch.Result
+	var err error
+	if *sessionFile != "" {
+		result, err = fetchWithSession(fetcher, url, *sessionFile, fetchOpts)
+	} else {
+		result, err = fetcher.Fetch(context.Background(), url, fetchOpts...)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "エラー: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *screenshot != "" {
+		if err := os.WriteFile(*screenshot, result.Screenshot, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: スクリーンショットの保存に失敗しました: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *harFile != "" {
+		if err := writeHAR(*harFile, result.HAR); err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: HARファイルの保存に失敗しました: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// 結果を出力
+	var w io.Writer = os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "エラー: 出力ファイルの作成に失敗しました: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
 	switch *output {
 	case "html":
-		fmt.Print(result.HTML)
+		fmt.Fprint(w, result.HTML)
 	case "markdown":
-		fmt.Print(result.Markdown)
+		fmt.Fprint(w, result.Markdown)
 	case "json":
-		outputJSON(result, *markdown)
+		outputJSON(w, result, *markdown)
 	case "stats":
-		outputStats(result)
+		outputStats(w, result)
+	case "pdf":
+		w.Write(result.PDF)
+	case "mhtml":
+		fmt.Fprint(w, result.MHTML)
 	default:
 		fmt.Fprintf(os.Stderr, "エラー: 不明な出力形式: %s\n", *output)
 		os.Exit(1)
 	}
 }
 
+// writeHAR はHARをJSONファイルに保存する
+func writeHAR(path string, har *htmlfetch.HAR) error {
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// pdfPapers は-pdf-paperで指定できる用紙サイズ
+var pdfPapers = map[string]htmlfetch.PaperSize{
+	"a4":     htmlfetch.PaperA4,
+	"a3":     htmlfetch.PaperA3,
+	"letter": htmlfetch.PaperLetter,
+	"legal":  htmlfetch.PaperLegal,
+}
+
+// headerFlags は-Hで複数指定されたリクエストヘッダー
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	parts := make([]string, 0, len(h))
+	for k, v := range h {
+		parts = append(parts, k+": "+v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("ヘッダーは\"Name: value\"形式で指定してください: %s", s)
+	}
+	if *h == nil {
+		*h = make(headerFlags)
+	}
+	(*h)[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+// loadActions は操作の配列を書いたJSONファイル・YAMLファイル（拡張子 .yaml/.yml）を読み込む
+func loadActions(path string) ([]htmlfetch.Action, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("アクションファイルの読み込みに失敗しました: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// JSONと同じ形式で解釈するため、YAMLをJSONに変換してからAction.UnmarshalJSONで読み込む
+		// 値は全て文字列のため、引用符のない数値（text: 1000001）も文字列として読む
+		var v []map[string]string
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("アクションファイルの解析に失敗しました: %s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("アクションファイルの解析に失敗しました: %s: %w", path, err)
+		}
+	}
+	var actions []htmlfetch.Action
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("アクションファイルの解析に失敗しました: %s: %w", path, err)
+	}
+	return actions, nil
+}
+
+// stringsFlag は複数回指定できる文字列フラグ
+type stringsFlag []string
+
+func (s stringsFlag) String() string {
+	return strings.Join(s, ", ")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// fetchWithSession はセッションファイルの状態を復元してフェッチし、更新後の状態を保存する
+// ファイルが存在しない場合は新しいセッションとして開始する
+func fetchWithSession(fetcher *htmlfetch.Fetcher, url, path string, opts []htmlfetch.FetchOption) (*htmlfetch.Result, error) {
+	if err := fetcher.Start(); err != nil {
+		return nil, err
+	}
+	defer fetcher.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	session, err := fetcher.NewSession(name)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	if _, statErr := os.Stat(path); statErr == nil {
+		if err := session.Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := session.Fetch(context.Background(), url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Save(path); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // parseViewport はビューポート文字列をパース
 func parseViewport(s string) (int, int) {
 	parts := strings.Split(s, "x")
@@ -130,6 +456,57 @@ func parseViewport(s string) (int, int) {
 	return w, h
 }
 
+// parseGeolocation は"緯度,経度[,精度]"形式の位置情報をパース（精度の省略時は100m）
+func parseGeolocation(s string) (float64, float64, float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("位置情報は\"緯度,経度[,精度]\"形式で指定してください: %s", s)
+	}
+	values := []float64{0, 0, 100}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("位置情報の数値が不正です: %s", p)
+		}
+		values[i] = v
+	}
+	return values[0], values[1], values[2], nil
+}
+
+// parsePDFMargin は"全体"または"上,右,下,左"形式のPDFの余白（インチ）をパース
+func parsePDFMargin(s string) (htmlfetch.PDFMargin, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 1 && len(parts) != 4 {
+		return htmlfetch.PDFMargin{}, fmt.Errorf("PDFの余白は\"全体\"または\"上,右,下,左\"形式で指定してください: %s", s)
+	}
+	values := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v < 0 {
+			return htmlfetch.PDFMargin{}, fmt.Errorf("PDFの余白の数値が不正です: %s", p)
+		}
+		values[i] = v
+	}
+	if len(values) == 1 {
+		return htmlfetch.PDFMargin{Top: values[0], Right: values[0], Bottom: values[0], Left: values[0]}, nil
+	}
+	return htmlfetch.PDFMargin{Top: values[0], Right: values[1], Bottom: values[2], Left: values[3]}, nil
+}
+
+// screenshotFormat はファイルの拡張子から画像形式を判定
+func screenshotFormat(path string) (htmlfetch.ScreenshotFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return htmlfetch.ScreenshotPNG, nil
+	case ".jpg", ".jpeg":
+		return htmlfetch.ScreenshotJPEG, nil
+	case ".webp":
+		return htmlfetch.ScreenshotWebP, nil
+	default:
+		return "", fmt.Errorf("未対応の画像形式: %s（.png/.jpg/.webp を指定してください）", path)
+	}
+}
+
 // parseWaitStrategy は待機戦略文字列をパース
 func parseWaitStrategy(s string) htmlfetch.WaitStrategy {
 	switch s {
@@ -145,14 +522,18 @@ func parseWaitStrategy(s string) htmlfetch.WaitStrategy {
 }
 
 // outputJSON はJSON形式で出力
-func outputJSON(result *htmlfetch.Result, includeMarkdown bool) {
+func outputJSON(w io.Writer, result *htmlfetch.Result, includeMarkdown bool) {
 	type jsonOutput struct {
-		FinalURL       string `json:"final_url"`
-		StatusCode     int    `json:"status_code"`
-		DurationMs     int64  `json:"duration_ms"`
-		HTMLLength     int    `json:"html_length"`
-		MarkdownLength int    `json:"markdown_length,omitempty"`
-		Markdown       string `json:"markdown,omitempty"`
+		FinalURL       string         `json:"final_url"`
+		StatusCode     int            `json:"status_code"`
+		Headers        http.Header    `json:"headers,omitempty"`
+		Redirects      []jsonRedirect `json:"redirects,omitempty"`
+		DurationMs     int64          `json:"duration_ms"`
+		HTMLLength     int            `json:"html_length"`
+		MarkdownLength int            `json:"markdown_length,omitempty"`
+		Markdown       string         `json:"markdown,omitempty"`
+		Removed        int            `json:"removed_elements,omitempty"`
+		Consent        *jsonConsent   `json:"consent,omitempty"`
 		Stats          struct {
 			TotalBytesIn   int64 `json:"total_bytes_in"`
 			TotalBytesOut  int64 `json:"total_bytes_out"`
@@ -161,15 +542,42 @@ func outputJSON(result *htmlfetch.Result, includeMarkdown bool) {
 				Count    int   `json:"count"`
 				BytesIn  int64 `json:"bytes_in"`
 				BytesOut int64 `json:"bytes_out"`
+				Blocked  int   `json:"blocked,omitempty"`
 			} `json:"by_resource_type,omitempty"`
+			HeaderBytesIn          int64          `json:"header_bytes_in"`
+			HeaderBytesOut         int64          `json:"header_bytes_out"`
+			CacheHits              int            `json:"cache_hits"`
+			ServiceWorkerResponses int            `json:"service_worker_responses"`
+			RedirectCount          int            `json:"redirect_count"`
+			RedirectBytesIn        int64          `json:"redirect_bytes_in"`
+			BlockedCount           int            `json:"blocked_count,omitempty"`
+			BlockedByRule          map[string]int `json:"blocked_by_rule,omitempty"`
+			EstimatedBytesSaved    int64          `json:"estimated_bytes_saved,omitempty"`
+			Failures               []jsonFailure  `json:"failures,omitempty"`
 		} `json:"stats"`
+		Timing    jsonTiming     `json:"timing"`
+		Responses []jsonResponse `json:"responses,omitempty"`
+		Console   []jsonConsole  `json:"console,omitempty"`
 	}
 
 	out := jsonOutput{
 		FinalURL:   result.FinalURL,
 		StatusCode: result.StatusCode,
+		Headers:    result.Headers,
 		DurationMs: result.Duration.Milliseconds(),
 		HTMLLength: len(result.HTML),
+		Removed:    result.RemovedElements,
+	}
+	if c := result.Consent; c != nil {
+		out.Consent = &jsonConsent{CMP: c.CMP, Method: c.Method, Closed: c.Closed}
+	}
+	for _, r := range result.Redirects {
+		out.Redirects = append(out.Redirects, jsonRedirect{
+			URL:      r.URL,
+			Status:   r.Status,
+			Location: r.Location,
+			Type:     r.Type,
+		})
 	}
 	if includeMarkdown && result.Markdown != "" {
 		out.MarkdownLength = len(result.Markdown)
@@ -178,47 +586,265 @@ func outputJSON(result *htmlfetch.Result, includeMarkdown bool) {
 	out.Stats.TotalBytesIn = result.Stats.TotalBytesIn
 	out.Stats.TotalBytesOut = result.Stats.TotalBytesOut
 	out.Stats.RequestCount = result.Stats.RequestCount
+	out.Stats.HeaderBytesIn = result.Stats.HeaderBytesIn
+	out.Stats.HeaderBytesOut = result.Stats.HeaderBytesOut
+	out.Stats.CacheHits = result.Stats.CacheHits
+	out.Stats.ServiceWorkerResponses = result.Stats.ServiceWorkerResponses
+	out.Stats.RedirectCount = result.Stats.RedirectCount
+	out.Stats.RedirectBytesIn = result.Stats.RedirectBytesIn
+	out.Stats.BlockedCount = result.Stats.BlockedCount
+	if len(result.Stats.BlockedByRule) > 0 {
+		out.Stats.BlockedByRule = result.Stats.BlockedByRule
+	}
+	out.Stats.EstimatedBytesSaved = result.Stats.EstimatedBytesSaved
+	for _, f := range result.Stats.Failures {
+		out.Stats.Failures = append(out.Stats.Failures, jsonFailure{
+			URL:          f.URL,
+			ResourceType: f.ResourceType,
+			Reason:       f.Reason,
+			BlockedBy:    f.BlockedBy,
+			ErrorText:    f.ErrorText,
+		})
+	}
 
 	if len(result.Stats.ByResourceType) > 0 {
 		out.Stats.ByResourceType = make(map[string]struct {
 			Count    int   `json:"count"`
 			BytesIn  int64 `json:"bytes_in"`
 			BytesOut int64 `json:"bytes_out"`
+			Blocked  int   `json:"blocked,omitempty"`
 		})
 		for k, v := range result.Stats.ByResourceType {
 			out.Stats.ByResourceType[k] = struct {
 				Count    int   `json:"count"`
 				BytesIn  int64 `json:"bytes_in"`
 				BytesOut int64 `json:"bytes_out"`
+				Blocked  int   `json:"blocked,omitempty"`
 			}{
 				Count:    v.Count,
 				BytesIn:  v.BytesIn,
 				BytesOut: v.BytesOut,
+				Blocked:  v.Blocked,
 			}
 		}
 	}
 
-	enc := json.NewEncoder(os.Stdout)
+	tm := result.Timing
+	out.Timing = jsonTiming{
+		SetupMs:            tm.Setup.Milliseconds(),
+		NavigationMs:       tm.Navigation.Milliseconds(),
+		WaitMs:             tm.Wait.Milliseconds(),
+		ConsentMs:          tm.Consent.Milliseconds(),
+		ActionsMs:          tm.Actions.Milliseconds(),
+		AutoScrollMs:       tm.AutoScroll.Milliseconds(),
+		SelectorWaitMs:     tm.SelectorWait.Milliseconds(),
+		TransformMs:        tm.Transform.Milliseconds(),
+		CaptureMs:          tm.Capture.Milliseconds(),
+		MarkdownMs:         tm.Markdown.Milliseconds(),
+		DNSMs:              tm.DNS.Milliseconds(),
+		ConnectMs:          tm.Connect.Milliseconds(),
+		TLSMs:              tm.TLS.Milliseconds(),
+		TTFBMs:             tm.TTFB.Milliseconds(),
+		DOMContentLoadedMs: tm.DOMContentLoaded.Milliseconds(),
+		LoadMs:             tm.Load.Milliseconds(),
+		FCPMs:              tm.FCP.Milliseconds(),
+		LCPMs:              tm.LCP.Milliseconds(),
+		CLS:                tm.CLS,
+		ScriptMs:           tm.ScriptDuration.Milliseconds(),
+		LayoutMs:           tm.LayoutDuration.Milliseconds(),
+		TaskMs:             tm.TaskDuration.Milliseconds(),
+		JSHeapUsedSize:     tm.JSHeapUsedSize,
+	}
+
+	for _, r := range result.Responses {
+		out.Responses = append(out.Responses, jsonResponse{
+			URL:       r.URL,
+			Method:    r.Method,
+			Status:    r.Status,
+			MIMEType:  r.MIMEType,
+			Body:      string(r.Body),
+			Truncated: r.Truncated,
+		})
+	}
+
+	for _, m := range result.Console {
+		out.Console = append(out.Console, jsonConsole{
+			Level:  m.Level,
+			Source: m.Source,
+			Text:   m.Text,
+			URL:    m.URL,
+			Line:   m.Line,
+		})
+	}
+
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	enc.Encode(out)
 }
 
+// jsonTiming は処理時間の内訳とパフォーマンス指標のJSON表現（時間はミリ秒）
+type jsonTiming struct {
+	SetupMs            int64   `json:"setup_ms"`
+	NavigationMs       int64   `json:"navigation_ms"`
+	WaitMs             int64   `json:"wait_ms"`
+	ConsentMs          int64   `json:"consent_ms"`
+	ActionsMs          int64   `json:"actions_ms"`
+	AutoScrollMs       int64   `json:"auto_scroll_ms"`
+	SelectorWaitMs     int64   `json:"selector_wait_ms"`
+	TransformMs        int64   `json:"transform_ms"`
+	CaptureMs          int64   `json:"capture_ms"`
+	MarkdownMs         int64   `json:"markdown_ms"`
+	DNSMs              int64   `json:"dns_ms"`
+	ConnectMs          int64   `json:"connect_ms"`
+	TLSMs              int64   `json:"tls_ms"`
+	TTFBMs             int64   `json:"ttfb_ms"`
+	DOMContentLoadedMs int64   `json:"dom_content_loaded_ms"`
+	LoadMs             int64   `json:"load_ms"`
+	FCPMs              int64   `json:"fcp_ms"`
+	LCPMs              int64   `json:"lcp_ms"`
+	CLS                float64 `json:"cls"`
+	ScriptMs           int64   `json:"script_ms"`
+	LayoutMs           int64   `json:"layout_ms"`
+	TaskMs             int64   `json:"task_ms"`
+	JSHeapUsedSize     int64   `json:"js_heap_used_size"`
+}
+
+// jsonConsent はCookie同意ダイアログの処理結果のJSON表現
+type jsonConsent struct {
+	CMP    string `json:"cmp"`
+	Method string `json:"method"`
+	Closed bool   `json:"closed"`
+}
+
+// jsonRedirect はリダイレクトの1ステップのJSON表現
+type jsonRedirect struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status"`
+	Location string `json:"location"`
+	Type     string `json:"type"`
+}
+
+// jsonResponse は-captureで記録したレスポンスのJSON表現
+type jsonResponse struct {
+	URL       string `json:"url"`
+	Method    string `json:"method"`
+	Status    int    `json:"status"`
+	MIMEType  string `json:"mime_type"`
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// jsonFailure は読み込みに失敗したリクエストのJSON表現
+type jsonFailure struct {
+	URL          string `json:"url"`
+	ResourceType string `json:"resource_type"`
+	Reason       string `json:"reason"`
+	BlockedBy    string `json:"blocked_by,omitempty"`
+	ErrorText    string `json:"error_text"`
+}
+
+// jsonConsole はコンソールメッセージのJSON表現
+type jsonConsole struct {
+	Level  string `json:"level"`
+	Source string `json:"source"`
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
+
 // outputStats は統計情報を人間が読みやすい形式で出力
-func outputStats(result *htmlfetch.Result) {
-	fmt.Printf("URL: %s\n", result.FinalURL)
-	fmt.Printf("Status: %d\n", result.StatusCode)
-	fmt.Printf("Duration: %v\n", result.Duration.Round(time.Millisecond))
-	fmt.Printf("HTML: %s\n", formatBytes(int64(len(result.HTML))))
-	fmt.Printf("Network: %s in / %s out (%d requests)\n",
+func outputStats(w io.Writer, result *htmlfetch.Result) {
+	fmt.Fprintf(w, "URL: %s\n", result.FinalURL)
+	fmt.Fprintf(w, "Status: %d\n", result.StatusCode)
+	for _, name := range []string{"Content-Type", "Cache-Control", "X-Robots-Tag"} {
+		if v := result.Headers.Get(name); v != "" {
+			fmt.Fprintf(w, "%s: %s\n", name, v)
+		}
+	}
+	if len(result.Redirects) > 0 {
+		fmt.Fprintln(w, "Redirect chain:")
+		for _, r := range result.Redirects {
+			fmt.Fprintf(w, "  [%s] %d %s -> %s\n", r.Type, r.Status, r.URL, r.Location)
+		}
+	}
+	fmt.Fprintf(w, "Duration: %v\n", result.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "HTML: %s\n", formatBytes(int64(len(result.HTML))))
+	if result.RemovedElements > 0 {
+		fmt.Fprintf(w, "Removed elements: %d\n", result.RemovedElements)
+	}
+	if c := result.Consent; c != nil {
+		fmt.Fprintf(w, "Consent: %s (%s, closed=%v)\n", c.CMP, c.Method, c.Closed)
+	}
+	fmt.Fprintf(w, "Network: %s in / %s out (%d requests)\n",
 		formatBytes(result.Stats.TotalBytesIn),
 		formatBytes(result.Stats.TotalBytesOut),
 		result.Stats.RequestCount,
 	)
+	fmt.Fprintf(w, "Headers: %s in / %s out\n",
+		formatBytes(result.Stats.HeaderBytesIn),
+		formatBytes(result.Stats.HeaderBytesOut),
+	)
+	if result.Stats.RedirectCount > 0 {
+		fmt.Fprintf(w, "Redirects: %d (%s in)\n", result.Stats.RedirectCount, formatBytes(result.Stats.RedirectBytesIn))
+	}
+	if result.Stats.CacheHits > 0 || result.Stats.ServiceWorkerResponses > 0 {
+		fmt.Fprintf(w, "Cache: %d hits / Service Worker: %d\n", result.Stats.CacheHits, result.Stats.ServiceWorkerResponses)
+	}
+
+	tm := result.Timing
+	ms := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+	fmt.Fprintln(w, "\n処理時間:")
+	fmt.Fprintf(w, "  準備 %s / 遷移 %s / 待機 %s / 同意 %s / 操作 %s / スクロール %s / セレクタ待機 %s / 変換 %s / 出力 %s / Markdown %s\n",
+		ms(tm.Setup), ms(tm.Navigation), ms(tm.Wait), ms(tm.Consent), ms(tm.Actions), ms(tm.AutoScroll), ms(tm.SelectorWait), ms(tm.Transform), ms(tm.Capture), ms(tm.Markdown))
+	fmt.Fprintf(w, "  DNS %s / 接続 %s (TLS %s) / TTFB %s / DOMContentLoaded %s / load %s\n",
+		ms(tm.DNS), ms(tm.Connect), ms(tm.TLS), ms(tm.TTFB), ms(tm.DOMContentLoaded), ms(tm.Load))
+	fmt.Fprintf(w, "  FCP %s / LCP %s / CLS %.3f / JS %s / レイアウト %s / JSヒープ %s\n",
+		ms(tm.FCP), ms(tm.LCP), tm.CLS, ms(tm.ScriptDuration), ms(tm.LayoutDuration), formatBytes(tm.JSHeapUsedSize))
 
 	if len(result.Stats.ByResourceType) > 0 {
-		fmt.Println("\nリソース別:")
+		fmt.Fprintln(w, "\nリソース別:")
 		for rtype, stat := range result.Stats.ByResourceType {
-			fmt.Printf("  %s: %d件, %s in\n", rtype, stat.Count, formatBytes(stat.BytesIn))
+			fmt.Fprintf(w, "  %s: %d件, %s in", rtype, stat.Count, formatBytes(stat.BytesIn))
+			if stat.Blocked > 0 {
+				fmt.Fprintf(w, " (%d件ブロック)", stat.Blocked)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if result.Stats.BlockedCount > 0 {
+		fmt.Fprintf(w, "\nブロック: %d件（推定 %s 削減）\n",
+			result.Stats.BlockedCount, formatBytes(result.Stats.EstimatedBytesSaved))
+		for rule, count := range result.Stats.BlockedByRule {
+			fmt.Fprintf(w, "  %s: %d件\n", rule, count)
+		}
+	}
+
+	// ブロックしたリクエストはルール別の件数のみ表示し、それ以外の失敗を1件ずつ表示する
+	var failures []htmlfetch.RequestFailure
+	for _, f := range result.Stats.Failures {
+		if f.BlockedBy == "" {
+			failures = append(failures, f)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintln(w, "\n失敗:")
+		for _, f := range failures {
+			fmt.Fprintf(w, "  [%s] %s %s (%s)\n", f.Reason, f.ResourceType, f.URL, f.ErrorText)
+		}
+	}
+
+	if len(result.Console) > 0 {
+		fmt.Fprintln(w, "\nコンソール:")
+		for _, m := range result.Console {
+			fmt.Fprintf(w, "  [%s] %s", m.Level, m.Text)
+			switch {
+			case m.URL != "" && m.Line > 0:
+				fmt.Fprintf(w, " (%s:%d)", m.URL, m.Line)
+			case m.URL != "":
+				fmt.Fprintf(w, " (%s)", m.URL)
+			}
+			fmt.Fprintln(w)
 		}
 	}
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/naozine/nz-html-fetch/pkg/htmlfetch"
+)
+
+// TestOutputJSON_BlockedByResourceType は-output=jsonでリソースタイプ別のブロック数が出力されることを検証する。
+func TestOutputJSON_BlockedByResourceType(t *testing.T) {
+	result := &htmlfetch.Result{
+		Stats: htmlfetch.NetworkStats{
+			ByResourceType: map[string]*htmlfetch.ResourceStat{
+				"Image":  {Count: 2, BytesIn: 1024, Blocked: 3},
+				"Script": {Count: 1, BytesIn: 512},
+			},
+			BlockedCount: 3,
+		},
+	}
+
+	var buf bytes.Buffer
+	outputJSON(&buf, result, false)
+
+	var out struct {
+		Stats struct {
+			ByResourceType map[string]struct {
+				Count   int `json:"count"`
+				Blocked int `json:"blocked"`
+			} `json:"by_resource_type"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("JSONのパースに失敗: %v\n%s", err, buf.String())
+	}
+	if got := out.Stats.ByResourceType["Image"]; got.Count != 2 || got.Blocked != 3 {
+		t.Errorf("Image: got %+v, want count=2 blocked=3", got)
+	}
+	if got := out.Stats.ByResourceType["Script"]; got.Blocked != 0 {
+		t.Errorf("Script: got %+v, want blocked=0", got)
+	}
+}
+
+// TestLoadActions は-actionsのJSONファイルとYAMLファイルが同じ操作として読み込まれることを検証する。
+func TestLoadActions(t *testing.T) {
+	want := []htmlfetch.Action{
+		htmlfetch.Click("#more"),
+		htmlfetch.Type("#q", "東京 天気"),
+		htmlfetch.Press("Enter"),
+		{Kind: htmlfetch.ActionWaitFor, Selector: ".results", Timeout: 15 * time.Second},
+		htmlfetch.Scroll(""),
+		htmlfetch.Sleep(500 * time.Millisecond),
+	}
+	files := map[string]string{
+		"steps.json": `[
+  {"type": "click", "selector": "#more"},
+  {"type": "type", "selector": "#q", "text": "東京 天気"},
+  {"type": "press", "key": "Enter"},
+  {"type": "wait_for", "selector": ".results", "timeout": "15s"},
+  {"type": "scroll"},
+  {"type": "sleep", "duration": "500ms"}
+]`,
+		"steps.yaml": `- type: click
+  selector: "#more"
+- type: type
+  selector: "#q"
+  text: 東京 天気
+- type: press
+  key: Enter
+- type: wait_for
+  selector: .results
+  timeout: 15s
+- type: scroll
+- type: sleep
+  duration: 500ms
+`,
+		"steps.yml": `[{type: click, selector: "#more"}, {type: type, selector: "#q", text: 東京 天気}, {type: press, key: Enter},
+  {type: wait_for, selector: .results, timeout: 15s}, {type: scroll}, {type: sleep, duration: 500ms}]`,
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadActions(path)
+			if err != nil {
+				t.Fatalf("loadActions: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %d件, want %d件", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("[%d] got %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestLoadActions_Invalid は不正な操作がJSON・YAMLのどちらでもエラーになることを検証する。
+func TestLoadActions_Invalid(t *testing.T) {
+	files := map[string]string{
+		"missing_selector.json": `[{"type": "click"}]`,
+		"missing_selector.yaml": "- type: click\n",
+		"unknown_type.yaml":     "- type: hover\n  selector: '#a'\n",
+		"bad_duration.yaml":     "- type: sleep\n  duration: 1 second\n",
+		"numeric_timeout.yaml":  "- type: wait_for\n  selector: '#a'\n  timeout: 10\n",
+		"broken.yaml":           "- type: click\n  selector: [\n",
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := loadActions(path); err == nil {
+				t.Errorf("エラーになりません: %+v", got)
+			}
+		})
+	}
+}
+
+// TestLoadActions_YAMLScalars はYAMLの引用符のない数値が文字列として読み込まれることを検証する。
+func TestLoadActions_YAMLScalars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "steps.yaml")
+	content := "- type: type\n  selector: '#zip'\n  text: 1000001\n- type: press\n  key: 1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadActions(path)
+	if err != nil {
+		t.Fatalf("loadActions: %v", err)
+	}
+	if len(got) != 2 || got[0] != htmlfetch.Type("#zip", "1000001") || got[1] != htmlfetch.Press("1") {
+		t.Errorf("got %+v", got)
+	}
+}
+
+// TestParsePDFMargin は-pdf-marginの"全体"・"上,右,下,左"形式をパースできることを検証する。
+func TestParsePDFMargin(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    htmlfetch.PDFMargin
+		wantErr bool
+	}{
+		{"0.4", htmlfetch.PDFMargin{Top: 0.4, Right: 0.4, Bottom: 0.4, Left: 0.4}, false},
+		{"0.4, 0.5, 0.6, 0.7", htmlfetch.PDFMargin{Top: 0.4, Right: 0.5, Bottom: 0.6, Left: 0.7}, false},
+		{"0.4,0.5", htmlfetch.PDFMargin{}, true},
+		{"1cm", htmlfetch.PDFMargin{}, true},
+		{"-1", htmlfetch.PDFMargin{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePDFMargin(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePDFMargin(%q): err=%v, wantErr=%v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePDFMargin(%q): got %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
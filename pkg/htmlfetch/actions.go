@@ -0,0 +1,220 @@
+package htmlfetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// defaultActionTimeout はAction.Timeoutが0の場合に要素を待つ時間
+const defaultActionTimeout = 10 * time.Second
+
+// actionKeys はpressで指定できるキー名（1文字のキーはそのまま指定できる）
+var actionKeys = map[string]input.Key{
+	"Enter":      input.Enter,
+	"Tab":        input.Tab,
+	"Escape":     input.Escape,
+	"Backspace":  input.Backspace,
+	"Delete":     input.Delete,
+	"Space":      input.Space,
+	"ArrowUp":    input.ArrowUp,
+	"ArrowDown":  input.ArrowDown,
+	"ArrowLeft":  input.ArrowLeft,
+	"ArrowRight": input.ArrowRight,
+	"PageUp":     input.PageUp,
+	"PageDown":   input.PageDown,
+	"Home":       input.Home,
+	"End":        input.End,
+}
+
+// Click は要素をクリックするAction
+func Click(selector string) Action {
+	return Action{Kind: ActionClick, Selector: selector}
+}
+
+// Type は要素（input・textarea等）の値を置き換えて文字列を入力するAction
+func Type(selector, text string) Action {
+	return Action{Kind: ActionType, Selector: selector, Text: text}
+}
+
+// Press はフォーカスされている要素でキーを押すAction（"Enter"・"Tab"・"Escape"・"ArrowDown"・"a"等）
+func Press(key string) Action {
+	return Action{Kind: ActionPress, Key: key}
+}
+
+// WaitFor は要素が表示されるまで待つAction
+func WaitFor(selector string) Action {
+	return Action{Kind: ActionWaitFor, Selector: selector}
+}
+
+// Scroll は要素が表示される位置までスクロールするAction
+// セレクタが空の場合はページの末尾までスクロールする
+func Scroll(selector string) Action {
+	return Action{Kind: ActionScroll, Selector: selector}
+}
+
+// Sleep は指定時間待つAction
+func Sleep(d time.Duration) Action {
+	return Action{Kind: ActionSleep, Duration: d}
+}
+
+// UnmarshalJSON は{"type": "sleep", "duration": "500ms"}のようなJSONからActionを読み込む
+// duration・timeoutはtime.ParseDurationの形式で指定する
+func (a *Action) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Type     ActionKind `json:"type"`
+		Selector string     `json:"selector"`
+		Text     string     `json:"text"`
+		Key      string     `json:"key"`
+		Duration string     `json:"duration"`
+		Timeout  string     `json:"timeout"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	parsed := Action{Kind: v.Type, Selector: v.Selector, Text: v.Text, Key: v.Key}
+	var err error
+	if v.Duration != "" {
+		if parsed.Duration, err = time.ParseDuration(v.Duration); err != nil {
+			return fmt.Errorf("durationが不正です: %w", err)
+		}
+	}
+	if v.Timeout != "" {
+		if parsed.Timeout, err = time.ParseDuration(v.Timeout); err != nil {
+			return fmt.Errorf("timeoutが不正です: %w", err)
+		}
+	}
+	if err := parsed.validate(); err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// validate は操作の種類に必要な値が指定されているかを検証する
+func (a Action) validate() error {
+	switch a.Kind {
+	case ActionClick, ActionType, ActionWaitFor:
+		if a.Selector == "" {
+			return fmt.Errorf("%sにはselectorが必要です", a.Kind)
+		}
+	case ActionPress:
+		if _, err := actionKey(a.Key); err != nil {
+			return err
+		}
+	case ActionScroll:
+	case ActionSleep:
+		if a.Duration <= 0 {
+			return errors.New("sleepにはdurationが必要です")
+		}
+	default:
+		return fmt.Errorf("不明な操作です: %q", a.Kind)
+	}
+	return nil
+}
+
+// actionKey はキー名をinput.Keyに変換する
+func actionKey(name string) (input.Key, error) {
+	if k, ok := actionKeys[name]; ok {
+		return k, nil
+	}
+	if r := []rune(name); len(r) == 1 {
+		return input.Key(r[0]), nil
+	}
+	return 0, fmt.Errorf("不明なキーです: %q", name)
+}
+
+// runActions は操作を順に実行し、失敗した時点でActionErrorを返す
+func runActions(page *rod.Page, actions []Action) error {
+	for i, a := range actions {
+		if err := runAction(page, a); err != nil {
+			return &ActionError{Index: i, Action: a, Err: err}
+		}
+	}
+	return nil
+}
+
+// runAction は1つの操作を実行する
+func runAction(page *rod.Page, a Action) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultActionTimeout
+	}
+
+	switch a.Kind {
+	case ActionClick:
+		el, cancel, err := findElement(page, a.Selector, timeout)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return el.Click(proto.InputMouseButtonLeft, 1)
+
+	case ActionType:
+		el, cancel, err := findElement(page, a.Selector, timeout)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		if err := el.SelectAllText(); err != nil {
+			return err
+		}
+		return el.Input(a.Text)
+
+	case ActionPress:
+		// page.Keyboardは元のページに紐づき、Context・Timeoutで複製したページのcontextを使わないため、
+		// キーイベントはタイムアウトを設定したページで直接送る
+		key, _ := actionKey(a.Key)
+		p := page.Timeout(timeout)
+		defer p.CancelTimeout()
+		if err := key.Encode(proto.InputDispatchKeyEventTypeKeyDown, 0).Call(p); err != nil {
+			return err
+		}
+		return key.Encode(proto.InputDispatchKeyEventTypeKeyUp, 0).Call(p)
+
+	case ActionWaitFor:
+		return waitForSelector(page, a.Selector, timeout)
+
+	case ActionScroll:
+		if a.Selector == "" {
+			_, err := page.Eval(`() => window.scrollTo(0, document.documentElement.scrollHeight)`)
+			return err
+		}
+		el, cancel, err := findElement(page, a.Selector, timeout)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return el.ScrollIntoView()
+
+	case ActionSleep:
+		select {
+		case <-time.After(a.Duration):
+			return nil
+		case <-page.GetContext().Done():
+			return page.GetContext().Err()
+		}
+	}
+	return nil
+}
+
+// findElement はセレクタに一致する要素が現れるまで待って返す
+// 要素の操作にもタイムアウトが適用されるため、操作が終わったらcancelを呼ぶ
+func findElement(page *rod.Page, selector string, timeout time.Duration) (*rod.Element, func(), error) {
+	p := page.Timeout(timeout)
+	el, err := p.Element(selector)
+	if err != nil {
+		p.CancelTimeout()
+		return nil, nil, fmt.Errorf("要素が見つかりませんでした: %w", err)
+	}
+	return el, func() { p.CancelTimeout() }, nil
+}
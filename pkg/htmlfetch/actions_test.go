@@ -0,0 +1,56 @@
+package htmlfetch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAction_UnmarshalJSON(t *testing.T) {
+	const steps = `[
+		{"type": "click", "selector": "#more", "timeout": "5s"},
+		{"type": "type", "selector": "#q", "text": "foo"},
+		{"type": "press", "key": "Enter"},
+		{"type": "wait_for", "selector": ".results"},
+		{"type": "scroll"},
+		{"type": "sleep", "duration": "500ms"}
+	]`
+	var got []Action
+	if err := json.Unmarshal([]byte(steps), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Action{
+		{Kind: ActionClick, Selector: "#more", Timeout: 5 * time.Second},
+		Type("#q", "foo"),
+		Press("Enter"),
+		WaitFor(".results"),
+		Scroll(""),
+		Sleep(500 * time.Millisecond),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d件, want %d件", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAction_UnmarshalJSON_Invalid(t *testing.T) {
+	tests := []string{
+		`{"type": "hover", "selector": "#a"}`,
+		`{"type": "click"}`,
+		`{"type": "press", "key": "NoSuchKey"}`,
+		`{"type": "sleep"}`,
+		`{"type": "sleep", "duration": "1 second"}`,
+		`{"type": "wait_for", "selector": "#a", "timeout": "abc"}`,
+	}
+	for _, s := range tests {
+		var a Action
+		if err := json.Unmarshal([]byte(s), &a); err == nil {
+			t.Errorf("%s: エラーになりません: %+v", s, a)
+		}
+	}
+}
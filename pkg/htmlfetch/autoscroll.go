@@ -0,0 +1,99 @@
+package htmlfetch
+
+import (
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+const (
+	// defaultMaxScrolls はAutoScrollOptions.MaxScrollsが0の場合のスクロール回数の上限
+	defaultMaxScrolls = 20
+	// defaultScrollIdleTimeout はAutoScrollOptions.IdleTimeoutが0の場合に新しい内容を待つ時間
+	defaultScrollIdleTimeout = time.Second
+)
+
+// autoScrollJS はページ末尾までのスクロールを繰り返し、最後にページの先頭に戻る
+// 遅延読み込みのIntersectionObserverが途中の要素にも反応するよう、ビューポートの高さずつスクロールする
+// 末尾に達した後は、ページが伸びるか、idleMsの間DOMが変化しなくなるまで待つ
+// （DOMが変化し続けるページでもidleMsの5倍で打ち切る）
+const autoScrollJS = `async (maxScrolls, maxHeight, idleMs) => {
+	const sleep = (ms) => new Promise(r => setTimeout(r, ms));
+	const root = document.scrollingElement || document.documentElement;
+	const limit = () => maxHeight > 0 ? Math.min(root.scrollHeight, maxHeight) : root.scrollHeight;
+
+	let lastMutation = Date.now();
+	const observer = new MutationObserver(() => { lastMutation = Date.now(); });
+	observer.observe(document, { childList: true, subtree: true });
+
+	let scrolls = 0;
+	try {
+		while (scrolls < maxScrolls) {
+			const before = root.scrollHeight;
+			const step = Math.max(window.innerHeight, 200);
+			while (window.scrollY + window.innerHeight < limit()) {
+				const y = window.scrollY;
+				window.scrollTo(0, Math.min(y + step, limit() - window.innerHeight));
+				await sleep(100);
+				if (window.scrollY <= y) break;
+			}
+			scrolls++;
+
+			const start = Date.now();
+			for (;;) {
+				await sleep(100);
+				const now = Date.now();
+				if (root.scrollHeight > before) break;
+				if (now - lastMutation >= idleMs && now - start >= idleMs) break;
+				if (now - start >= idleMs * 5) break;
+			}
+			if (root.scrollHeight <= before) break;
+			if (maxHeight > 0 && root.scrollHeight >= maxHeight) break;
+		}
+	} finally {
+		observer.disconnect();
+	}
+	window.scrollTo(0, 0);
+}`
+
+// promoteLazyJS は遅延読み込み用の属性（data-src・data-srcset等）をsrc・srcsetに昇格し、
+// loading="lazy"を外して、昇格した画像の読み込みをidleMsまで待つ
+const promoteLazyJS = `async (idleMs) => {
+	const attrs = [
+		['data-src', 'src'], ['data-lazy-src', 'src'], ['data-original', 'src'],
+		['data-srcset', 'srcset'], ['data-lazy-srcset', 'srcset'],
+	];
+	const pending = [];
+	document.querySelectorAll('img, source, iframe, video').forEach(el => {
+		const done = new Set();
+		let changed = false;
+		for (const [from, to] of attrs) {
+			const v = el.getAttribute(from);
+			if (!v || done.has(to)) continue;
+			done.add(to);
+			if (el.getAttribute(to) !== v) {
+				el.setAttribute(to, v);
+				changed = true;
+			}
+		}
+		if (el.getAttribute('loading') === 'lazy') {
+			el.setAttribute('loading', 'eager');
+		}
+		if (changed && el.tagName === 'IMG' && !el.complete) {
+			pending.push(new Promise(r => { el.addEventListener('load', r); el.addEventListener('error', r); }));
+		}
+	});
+	if (pending.length > 0) {
+		await Promise.race([Promise.all(pending), new Promise(r => setTimeout(r, idleMs))]);
+	}
+}`
+
+// autoScroll は無限スクロールの続きを読み込み、遅延読み込みの画像をsrc・srcsetに展開する
+func autoScroll(page *rod.Page, opts AutoScrollOptions) error {
+	idleMs := opts.IdleTimeout.Milliseconds()
+	if _, err := page.Eval(autoScrollJS, opts.MaxScrolls, opts.MaxHeight, idleMs); err != nil {
+		return err
+	}
+	_, err := page.Eval(promoteLazyJS, idleMs)
+	return err
+}
@@ -48,9 +48,68 @@ func (b blockingSet) isEmpty() bool {
 	return len(b) == 0
 }
 
+// blocker はリクエストをブロックするかを判定する
+type blocker struct {
+	blockSet blockingSet
+	blockAds bool
+	filters  *filterList // WithFilterListsで読み込んだフィルタリスト（組み込みの広告ドメインの代わりに使う）
+	allow    []*blockRuleMatcher
+	block    []*blockRuleMatcher
+}
+
+// newBlocker はBlockingOptions・BlockRule・フィルタリストからblockerを作成
+func newBlocker(opts BlockingOptions, rules []BlockRule, filters *filterList) *blocker {
+	b := &blocker{
+		blockSet: newBlockingSet(opts),
+		blockAds: opts.Ads,
+		filters:  filters,
+	}
+	for _, r := range rules {
+		if r.Allow {
+			b.allow = append(b.allow, newBlockRuleMatcher(r))
+		} else {
+			b.block = append(b.block, newBlockRuleMatcher(r))
+		}
+	}
+	return b
+}
+
+// isEmpty はブロック対象がないかを判定
+func (b *blocker) isEmpty() bool {
+	return b.blockSet.isEmpty() && !b.blockAds && b.filters == nil && len(b.block) == 0
+}
+
+// rule はリクエストをブロックするルールを返す（ブロックしない場合は空文字列）
+// 許可ルールに一致した場合はブロックしない。リソースタイプによるブロックはリソースタイプ名、
+// 広告ドメイン・フィルタリストによるブロックはBlockRuleAds、BlockRuleによるブロックはルール名を返す
+// documentURLはメインフレームのドキュメントのURLで、フィルタリストのサードパーティ判定等に使う
+func (b *blocker) rule(resourceType, reqURL, documentURL string) string {
+	for _, m := range b.allow {
+		if m.match(resourceType, reqURL) {
+			return ""
+		}
+	}
+	if b.blockSet.shouldBlock(resourceType) {
+		return resourceType
+	}
+	if b.filters != nil {
+		if b.filters.match(resourceType, reqURL, documentURL) {
+			return BlockRuleAds
+		}
+	} else if b.blockAds && isAdURL(reqURL) {
+		return BlockRuleAds
+	}
+	for _, m := range b.block {
+		if m.match(resourceType, reqURL) {
+			return m.name
+		}
+	}
+	return ""
+}
+
 // setupFetchBlocking はCDP Fetchドメインでリソースブロッキングを設定
-func setupFetchBlocking(page *rod.Page, blockSet blockingSet, blockAds bool) {
-	if blockSet.isEmpty() && !blockAds {
+func setupFetchBlocking(page *rod.Page, b *blocker) {
+	if b.isEmpty() {
 		return
 	}
 
@@ -61,21 +120,15 @@ func setupFetchBlocking(page *rod.Page, blockSet blockingSet, blockAds bool) {
 	_ = proto.FetchEnable{Patterns: patterns}.Call(page)
 
 	// リクエストを監視
+	// メインフレームのドキュメントのURLはstatsCollectorと同じ順序で追跡するため、判定が一致する
+	var documentURL string
 	go page.EachEvent(func(e *proto.FetchRequestPaused) {
-		reqURL := e.Request.URL
-		resourceType := string(e.ResourceType)
-
-		// リソースタイプによるブロック
-		if blockSet.shouldBlock(resourceType) {
-			_ = proto.FetchFailRequest{
-				RequestID:   e.RequestID,
-				ErrorReason: proto.NetworkErrorReasonBlockedByClient,
-			}.Call(page)
-			return
+		if e.ResourceType == proto.NetworkResourceTypeDocument && e.FrameID == page.FrameID {
+			documentURL = e.Request.URL
 		}
 
-		// 広告ドメインによるブロック
-		if blockAds && isAdURL(reqURL) {
+		// リソースタイプ・広告ドメイン・ブロックルールによるブロック
+		if b.rule(string(e.ResourceType), e.Request.URL, documentURL) != "" {
 			_ = proto.FetchFailRequest{
 				RequestID:   e.RequestID,
 				ErrorReason: proto.NetworkErrorReasonBlockedByClient,
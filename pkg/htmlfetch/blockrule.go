@@ -0,0 +1,93 @@
+package htmlfetch
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// blockRuleMatcher はBlockRuleをコンパイルしたもの
+type blockRuleMatcher struct {
+	name          string
+	glob          *globPattern
+	re            *regexp.Regexp
+	domains       []string
+	resourceTypes []string
+}
+
+// newBlockRuleMatcher はBlockRuleからblockRuleMatcherを作成
+func newBlockRuleMatcher(r BlockRule) *blockRuleMatcher {
+	m := &blockRuleMatcher{
+		name:          r.Name,
+		re:            r.URLRegexp,
+		resourceTypes: r.ResourceTypes,
+	}
+	if m.name == "" {
+		m.name = BlockRuleCustom
+	}
+	if r.URLGlob != "" {
+		m.glob = compileGlob(r.URLGlob)
+	}
+	m.domains = normalizeDomains(r.Domains)
+	return m
+}
+
+// normalizeDomains はドメインを小文字にし、先頭の"*."・"."を取り除く
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(d), "*."), ".")
+		if d != "" {
+			normalized = append(normalized, d)
+		}
+	}
+	return normalized
+}
+
+// match はリクエストがルールの条件を全て満たすかを判定
+func (m *blockRuleMatcher) match(resourceType, reqURL string) bool {
+	if len(m.resourceTypes) > 0 {
+		found := false
+		for _, t := range m.resourceTypes {
+			if strings.EqualFold(t, resourceType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.glob != nil && !m.glob.match(reqURL) {
+		return false
+	}
+	if m.re != nil && !m.re.MatchString(reqURL) {
+		return false
+	}
+	if len(m.domains) > 0 && !matchDomain(hostOf(reqURL), m.domains) {
+		return false
+	}
+	return true
+}
+
+// matchDomain はホストがいずれかのドメインまたはそのサブドメインかを判定
+func matchDomain(host string, domains []string) bool {
+	if host == "" {
+		return false
+	}
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf はURLのホスト名を小文字で返す（解析できない場合は空文字列）
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,44 @@
+package htmlfetch
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestBlocker_Rule(t *testing.T) {
+	b := newBlocker(BlockingOptions{Font: true}, []BlockRule{
+		{Name: "tracking", URLGlob: "*/tracking/*"},
+		{Name: "cdn-images", Domains: []string{"cdn.example.net"}, ResourceTypes: []string{"Image"}},
+		{URLRegexp: regexp.MustCompile(`\.gif$`)},
+		{Allow: true, Domains: []string{"static.example.com"}},
+		{Allow: true, URLGlob: "https://fonts.example.org/*", ResourceTypes: []string{"Font"}},
+	}, nil)
+
+	tests := []struct {
+		resourceType string
+		url          string
+		want         string
+	}{
+		{"Script", "https://example.com/tracking/t.js", "tracking"},
+		{"Script", "https://example.com/app.js", ""},
+		{"Image", "https://cdn.example.net/a.png", "cdn-images"},
+		{"Image", "https://img.cdn.example.net/a.png", "cdn-images"},
+		{"Script", "https://cdn.example.net/a.js", ""},
+		{"Image", "https://notcdn.example.net/a.png", ""},
+		{"Image", "https://example.com/spacer.gif", BlockRuleCustom},
+		// 許可ルールはブロックルール・BlockingOptionsより優先する
+		{"Script", "https://static.example.com/tracking/t.js", ""},
+		{"Image", "https://static.example.com/spacer.gif", ""},
+		{"Font", "https://example.com/a.woff2", "Font"},
+		{"Font", "https://fonts.example.org/a.woff2", ""},
+	}
+	for _, tt := range tests {
+		if got := b.rule(tt.resourceType, tt.url, ""); got != tt.want {
+			t.Errorf("rule(%q, %q) = %q, want %q", tt.resourceType, tt.url, got, tt.want)
+		}
+	}
+
+	if !newBlocker(BlockingOptions{}, []BlockRule{{Allow: true, URLGlob: "*"}}, nil).isEmpty() {
+		t.Error("許可ルールのみの場合はブロック対象なしとみなす")
+	}
+}
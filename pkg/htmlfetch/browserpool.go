@@ -0,0 +1,408 @@
+package htmlfetch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BrowserPool は複数のブラウザプロセスを管理するプール
+// 応答しなくなったプロセスや、一定回数フェッチしたプロセス・メモリ上限を超えたプロセスを
+// 自動的に再起動するため、長時間稼働するデーモンから利用できる
+type BrowserPool struct {
+	config   browserPoolConfig
+	members  []*poolMember
+	mu       sync.Mutex
+	changed  chan struct{}
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	started  bool
+	restarts int
+}
+
+// poolMember はプール内の1ブラウザプロセス
+type poolMember struct {
+	fetcher    *Fetcher
+	inflight   int  // 実行中のFetch数
+	fetches    int  // 起動後のFetch回数
+	retiring   bool // 再起動待ち（新しいFetchを割り当てない）
+	restarting bool // 再起動中
+}
+
+// available は新しいFetchを割り当て可能かを返す
+func (m *poolMember) available() bool {
+	return !m.retiring && !m.restarting
+}
+
+// BrowserPool設定（内部状態）
+type browserPoolConfig struct {
+	size           int
+	recycleAfter   int
+	maxRSS         int64
+	healthInterval time.Duration
+	fetcherOpts    []Option
+}
+
+// PoolOption はBrowserPool作成時のオプション
+type PoolOption func(*browserPoolConfig)
+
+// WithPoolSize はブラウザプロセス数を指定（デフォルト: 2）
+func WithPoolSize(n int) PoolOption {
+	return func(c *browserPoolConfig) {
+		c.size = n
+	}
+}
+
+// WithRecycleAfter は指定回数フェッチしたプロセスを再起動する
+func WithRecycleAfter(n int) PoolOption {
+	return func(c *browserPoolConfig) {
+		c.recycleAfter = n
+	}
+}
+
+// WithMaxRSS はプロセスツリーのRSSが指定バイト数を超えたら再起動する（Linuxのみ）
+func WithMaxRSS(bytes int64) PoolOption {
+	return func(c *browserPoolConfig) {
+		c.maxRSS = bytes
+	}
+}
+
+// WithHealthCheckInterval はヘルスチェックの間隔を指定（デフォルト: 30秒）
+func WithHealthCheckInterval(d time.Duration) PoolOption {
+	return func(c *browserPoolConfig) {
+		c.healthInterval = d
+	}
+}
+
+// WithFetcherOptions は各ブラウザプロセスのFetcherオプションを指定
+func WithFetcherOptions(opts ...Option) PoolOption {
+	return func(c *browserPoolConfig) {
+		c.fetcherOpts = append(c.fetcherOpts, opts...)
+	}
+}
+
+// NewBrowserPool は新しいBrowserPoolを作成
+func NewBrowserPool(opts ...PoolOption) *BrowserPool {
+	cfg := browserPoolConfig{
+		size:           2,
+		healthInterval: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.size < 1 {
+		cfg.size = 1
+	}
+	return &BrowserPool{
+		config:  cfg,
+		changed: make(chan struct{}),
+	}
+}
+
+// Start は全てのブラウザプロセスを起動し、ヘルスチェックを開始する
+// 使用後は必ずClose()を呼ぶこと
+func (p *BrowserPool) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started {
+		return nil
+	}
+
+	members := make([]*poolMember, 0, p.config.size)
+	for i := 0; i < p.config.size; i++ {
+		f := New(p.config.fetcherOpts...)
+		if err := f.Start(); err != nil {
+			for _, m := range members {
+				_ = m.fetcher.Close()
+			}
+			return err
+		}
+		members = append(members, &poolMember{fetcher: f})
+	}
+
+	p.members = members
+	p.stop = make(chan struct{})
+	p.started = true
+
+	p.wg.Add(1)
+	go p.healthLoop()
+	return nil
+}
+
+// Close はヘルスチェックを停止し、全てのブラウザプロセスを終了する
+func (p *BrowserPool) Close() error {
+	p.mu.Lock()
+	if !p.started {
+		p.mu.Unlock()
+		return nil
+	}
+	p.started = false
+	close(p.stop)
+	p.notifyLocked()
+	p.mu.Unlock()
+
+	// ヘルスチェックと再起動中の処理が終わるのを待つ
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var firstErr error
+	for _, m := range p.members {
+		if err := m.fetcher.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	p.members = nil
+	return firstErr
+}
+
+// Restarts はこれまでにブラウザプロセスを再起動した回数を返す
+func (p *BrowserPool) Restarts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.restarts
+}
+
+// Fetch は空いているブラウザプロセスでURLからHTMLを取得
+// ブラウザが応答しなくなっていた場合は、そのプロセスを再起動対象にして別のプロセスで1回だけ再試行する
+func (p *BrowserPool) Fetch(ctx context.Context, url string, opts ...FetchOption) (*Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		m, err := p.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := m.fetcher.Fetch(ctx, url, opts...)
+		healthy := err == nil || m.fetcher.ping(5*time.Second)
+		p.release(m, healthy)
+
+		if err == nil || healthy || ctx.Err() != nil {
+			return result, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// acquire は割り当て可能なプロセスのうち実行中のFetchが最も少ないものを返す
+// 全プロセスが再起動中の場合は、いずれかが使えるようになるまで待機する
+func (p *BrowserPool) acquire(ctx context.Context) (*poolMember, error) {
+	for {
+		p.mu.Lock()
+		if !p.started {
+			p.mu.Unlock()
+			return nil, &FetchError{
+				Code:    ErrInternalError,
+				Message: "BrowserPoolが起動していません",
+			}
+		}
+
+		var best *poolMember
+		for _, m := range p.members {
+			if m.available() && (best == nil || m.inflight < best.inflight) {
+				best = m
+			}
+		}
+		if best != nil {
+			best.inflight++
+			best.fetches++
+			if p.config.recycleAfter > 0 && best.fetches >= p.config.recycleAfter {
+				best.retiring = true
+			}
+			p.mu.Unlock()
+			return best, nil
+		}
+
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, &FetchError{
+				Code:    ErrFetchTimeout,
+				Message: "ブラウザの空き待ちがタイムアウトしました",
+				Cause:   ctx.Err(),
+			}
+		}
+	}
+}
+
+// release はFetchの終了を記録し、必要であればプロセスを再起動する
+func (p *BrowserPool) release(m *poolMember, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m.inflight--
+	if !healthy {
+		m.retiring = true
+	}
+	p.scheduleRestartLocked(m)
+	p.notifyLocked()
+}
+
+// scheduleRestartLocked は再起動待ちで実行中のFetchがないプロセスの再起動を開始する
+// p.muを保持した状態で呼ぶこと
+func (p *BrowserPool) scheduleRestartLocked(m *poolMember) {
+	if !p.started || !m.retiring || m.restarting || m.inflight > 0 {
+		return
+	}
+	m.restarting = true
+	p.wg.Add(1)
+	go p.restart(m)
+}
+
+// restart はブラウザプロセスを終了して起動し直す
+// 起動に失敗した場合は再起動待ちのまま残し、次のヘルスチェックで再試行する
+func (p *BrowserPool) restart(m *poolMember) {
+	defer p.wg.Done()
+
+	_ = m.fetcher.Close()
+	err := m.fetcher.Start()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m.restarting = false
+	if err == nil {
+		m.retiring = false
+		m.fetches = 0
+		p.restarts++
+	}
+	p.notifyLocked()
+}
+
+// notifyLocked は待機中のacquireに状態の変化を通知する
+// p.muを保持した状態で呼ぶこと
+func (p *BrowserPool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// healthLoop は一定間隔でヘルスチェックを実行する
+func (p *BrowserPool) healthLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth は各プロセスがCDPに応答するか、メモリ上限を超えていないかを確認する
+func (p *BrowserPool) checkHealth() {
+	p.mu.Lock()
+	members := append([]*poolMember(nil), p.members...)
+	p.mu.Unlock()
+
+	for _, m := range members {
+		p.mu.Lock()
+		skip := m.restarting
+		p.mu.Unlock()
+		if skip {
+			continue
+		}
+
+		healthy := m.fetcher.ping(5 * time.Second)
+		if healthy && p.config.maxRSS > 0 {
+			if rss, err := processTreeRSS(m.fetcher.pid()); err == nil && rss > p.config.maxRSS {
+				healthy = false
+			}
+		}
+
+		p.mu.Lock()
+		if !healthy {
+			m.retiring = true
+		}
+		p.scheduleRestartLocked(m)
+		p.mu.Unlock()
+	}
+}
+
+// processTreeRSS はプロセスとその子孫プロセスのRSS合計をバイト数で返す
+// /procを参照するためLinux以外ではエラーを返す
+func processTreeRSS(pid int) (int64, error) {
+	if pid <= 0 {
+		return 0, fmt.Errorf("不正なプロセスID: %d", pid)
+	}
+
+	rss, err := processRSS(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, child := range childProcesses(pid) {
+		if childRSS, err := processTreeRSS(child); err == nil {
+			rss += childRSS
+		}
+	}
+	return rss, nil
+}
+
+// processRSS は/proc/<pid>/statusのVmRSSをバイト数で返す
+func processRSS(pid int) (int64, error) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	return 0, scanner.Err()
+}
+
+// childProcesses は/proc/<pid>/task/*/childrenから子プロセスのIDを返す
+func childProcesses(pid int) []int {
+	tasks, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return nil
+	}
+
+	var children []int
+	for _, task := range tasks {
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/task/%s/children", pid, task.Name()))
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(data)) {
+			if child, err := strconv.Atoi(field); err == nil {
+				children = append(children, child)
+			}
+		}
+	}
+	return children
+}
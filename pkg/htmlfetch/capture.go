@@ -0,0 +1,134 @@
+package htmlfetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+)
+
+// captureScreenshot はスクリーンショットを撮影する
+// ページ全体・要素の撮影でもビューポートは変更しないため、撮影によってDOMが変化しない
+func captureScreenshot(page *rod.Page, opts ScreenshotOptions) ([]byte, error) {
+	format := proto.PageCaptureScreenshotFormat(opts.Format)
+	if format == "" {
+		format = proto.PageCaptureScreenshotFormatPng
+	}
+	req := proto.PageCaptureScreenshot{Format: format}
+	if format != proto.PageCaptureScreenshotFormatPng && opts.Quality > 0 {
+		req.Quality = gson.Int(opts.Quality)
+	}
+
+	switch {
+	case opts.Selector != "":
+		clip, err := elementClip(page, opts.Selector)
+		if err != nil {
+			return nil, err
+		}
+		req.Clip = clip
+		req.CaptureBeyondViewport = true
+	case opts.FullPage:
+		metrics, err := proto.PageGetLayoutMetrics{}.Call(page)
+		if err != nil {
+			return nil, err
+		}
+		if metrics.CSSContentSize == nil {
+			return nil, errors.New("ページサイズを取得できませんでした")
+		}
+		req.Clip = &proto.PageViewport{
+			Width:  metrics.CSSContentSize.Width,
+			Height: metrics.CSSContentSize.Height,
+			Scale:  1,
+		}
+		req.CaptureBeyondViewport = true
+	}
+
+	shot, err := req.Call(page)
+	if err != nil {
+		return nil, err
+	}
+	return shot.Data, nil
+}
+
+// elementClip はセレクタに一致する要素のドキュメント座標上の領域を返す
+func elementClip(page *rod.Page, selector string) (*proto.PageViewport, error) {
+	el, err := page.Sleeper(rod.NotFoundSleeper).Element(selector)
+	if err != nil {
+		return nil, fmt.Errorf("セレクタ '%s' が見つかりませんでした: %w", selector, err)
+	}
+
+	res, err := el.Eval(`function() {
+		const r = this.getBoundingClientRect();
+		return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+	}`)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip proto.PageViewport
+	if err := res.Value.Unmarshal(&clip); err != nil {
+		return nil, err
+	}
+	if clip.Width == 0 || clip.Height == 0 {
+		return nil, fmt.Errorf("セレクタ '%s' の要素が表示されていません", selector)
+	}
+	clip.Scale = 1
+	return &clip, nil
+}
+
+// printPDF はページを印刷用メディアでレンダリングしてPDFを生成する
+func printPDF(page *rod.Page, opts PDFOptions) ([]byte, error) {
+	paper := opts.Paper
+	if paper.Width == 0 || paper.Height == 0 {
+		paper = PaperA4
+	}
+
+	req := &proto.PagePrintToPDF{
+		Landscape:           opts.Landscape,
+		PrintBackground:     opts.PrintBackground,
+		PaperWidth:          gson.Num(paper.Width),
+		PaperHeight:         gson.Num(paper.Height),
+		PageRanges:          opts.PageRanges,
+		DisplayHeaderFooter: opts.HeaderTemplate != "" || opts.FooterTemplate != "",
+		HeaderTemplate:      opts.HeaderTemplate,
+		FooterTemplate:      opts.FooterTemplate,
+	}
+	if opts.Scale > 0 {
+		req.Scale = gson.Num(opts.Scale)
+	}
+	if m := opts.Margin; m != nil {
+		req.MarginTop = gson.Num(m.Top)
+		req.MarginBottom = gson.Num(m.Bottom)
+		req.MarginLeft = gson.Num(m.Left)
+		req.MarginRight = gson.Num(m.Right)
+	}
+	// 片方のテンプレートのみ指定した場合、もう片方にはChromiumのデフォルト（日付・タイトル等）が
+	// 表示されてしまうため空の要素で置き換える
+	if req.DisplayHeaderFooter {
+		if req.HeaderTemplate == "" {
+			req.HeaderTemplate = "<span></span>"
+		}
+		if req.FooterTemplate == "" {
+			req.FooterTemplate = "<span></span>"
+		}
+	}
+
+	r, err := page.PDF(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// captureMHTML はページとサブリソース（画像・CSS・フォント・iframe）をMHTML形式で保存する
+func captureMHTML(page *rod.Page) (string, error) {
+	snapshot, err := proto.PageCaptureSnapshot{Format: proto.PageCaptureSnapshotFormatMhtml}.Call(page)
+	if err != nil {
+		return "", err
+	}
+	return snapshot.Data, nil
+}
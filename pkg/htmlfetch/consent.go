@@ -0,0 +1,122 @@
+package htmlfetch
+
+import (
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+const (
+	// consentDetectTimeout はCMPの読み込みを検出してからダイアログの表示を待つ時間
+	consentDetectTimeout = 3 * time.Second
+	// consentHideTimeout はボタンのクリック・API呼び出し後にダイアログが閉じるのを待つ時間
+	consentHideTimeout = 3 * time.Second
+)
+
+// consentJS は既知のCMP（同意管理プラットフォーム）のダイアログを検出し、同意・拒否する
+// ページにCMPのスクリプト・グローバル変数がない場合はすぐに終了する
+// 表示されているボタンがあればクリックし、なければCMPのJS APIを呼ぶ
+const consentJS = `async (action, detectTimeout, hideTimeout) => {
+	const cmps = [
+		{
+			name: 'onetrust', dialog: '#onetrust-banner-sdk',
+			accept: '#onetrust-accept-btn-handler', reject: '#onetrust-reject-all-handler',
+			loaded: () => window.OneTrust || document.querySelector('script[src*="otSDKStub"], script[src*="cookielaw.org"], script[src*="onetrust"]'),
+			api: { accept: () => window.OneTrust.AllowAll(), reject: () => window.OneTrust.RejectAll() },
+		},
+		{
+			name: 'cookiebot', dialog: '#CybotCookiebotDialog',
+			accept: '#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll, #CybotCookiebotDialogBodyButtonAccept',
+			reject: '#CybotCookiebotDialogBodyButtonDecline',
+			loaded: () => window.Cookiebot || document.querySelector('script[src*="cookiebot.com"]'),
+			api: {
+				accept: () => window.Cookiebot.submitCustomConsent(true, true, true),
+				reject: () => window.Cookiebot.submitCustomConsent(false, false, false),
+			},
+		},
+		{
+			name: 'quantcast', dialog: '.qc-cmp2-container',
+			accept: '.qc-cmp2-summary-buttons button[mode="primary"]',
+			reject: '.qc-cmp2-summary-buttons button[mode="secondary"]',
+			loaded: () => document.querySelector('script[src*="quantcast"], script[src*="quantserve"]'),
+		},
+		{
+			name: 'trustarc', dialog: '#truste-consent-track',
+			accept: '#truste-consent-button', reject: '#truste-consent-required',
+			loaded: () => window.truste || document.querySelector('script[src*="trustarc"], script[src*="truste.com"]'),
+		},
+		{
+			name: 'didomi', dialog: '#didomi-host .didomi-popup, #didomi-host .didomi-notice',
+			accept: '#didomi-notice-agree-button', reject: '#didomi-notice-disagree-button',
+			loaded: () => window.Didomi || document.querySelector('script[src*="didomi"]'),
+			api: { accept: () => window.Didomi.setUserAgreeToAll(), reject: () => window.Didomi.setUserDisagreeToAll() },
+		},
+		{
+			// ダイアログはShadow DOM内にあるためAPIで操作し、同意が必要かどうかで表示を判定する
+			name: 'usercentrics', dialog: '#usercentrics-root',
+			open: () => !!document.querySelector('#usercentrics-root') &&
+				!!(window.UC_UI && window.UC_UI.isConsentRequired && window.UC_UI.isConsentRequired()),
+			loaded: () => window.UC_UI || document.querySelector('script[src*="usercentrics"]'),
+			api: { accept: () => window.UC_UI.acceptAllConsents(), reject: () => window.UC_UI.denyAllConsents() },
+		},
+	];
+
+	const sleep = (ms) => new Promise(r => setTimeout(r, ms));
+	const visible = (el) => {
+		if (!el || !el.isConnected) return false;
+		const style = getComputedStyle(el);
+		if (style.display === 'none' || style.visibility === 'hidden' || style.opacity === '0') return false;
+		return el.getClientRects().length > 0;
+	};
+	const shown = (cmp) => cmp.open ? cmp.open() : visible(document.querySelector(cmp.dialog));
+
+	const candidates = cmps.filter(cmp => cmp.loaded() || document.querySelector(cmp.dialog));
+	if (candidates.length === 0) return null;
+
+	// CMPのスクリプトが読み込まれていれば、ダイアログが表示されるまで待つ
+	let cmp = null;
+	for (const deadline = Date.now() + detectTimeout; !cmp && Date.now() < deadline; ) {
+		cmp = candidates.find(shown);
+		if (!cmp) await sleep(100);
+	}
+	if (!cmp) return null;
+
+	let method = '';
+	const button = cmp[action] && document.querySelector(cmp[action]);
+	if (visible(button)) {
+		button.click();
+		method = 'click';
+	} else if (cmp.api) {
+		try {
+			cmp.api[action]();
+			method = 'api';
+		} catch (e) {}
+	}
+	if (!method) return { cmp: cmp.name, method: '', closed: false };
+
+	// ダイアログ（とオーバーレイ）が閉じるまで待つ
+	for (const deadline = Date.now() + hideTimeout; Date.now() < deadline; await sleep(100)) {
+		if (!shown(cmp)) {
+			return { cmp: cmp.name, method, closed: true };
+		}
+	}
+	return { cmp: cmp.name, method, closed: false };
+}`
+
+// handleConsent は既知のCMPのCookie同意ダイアログを検出し、指定した操作で閉じる
+// CMPを検出しなかった場合はnilを返す
+func handleConsent(page *rod.Page, action ConsentAction) (*ConsentResult, error) {
+	res, err := page.Eval(consentJS, string(action),
+		consentDetectTimeout.Milliseconds(), consentHideTimeout.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	if res.Value.Nil() {
+		return nil, nil
+	}
+	return &ConsentResult{
+		CMP:    res.Value.Get("cmp").Str(),
+		Method: res.Value.Get("method").Str(),
+		Closed: res.Value.Get("closed").Bool(),
+	}, nil
+}
@@ -0,0 +1,152 @@
+package htmlfetch
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// consoleCollector はコンソール出力・未捕捉の例外・ブラウザのログを収集する
+type consoleCollector struct {
+	messages []ConsoleMessage
+	mu       sync.Mutex
+}
+
+// setupConsoleCapture はRuntime・Logドメインを有効化してイベントの収集を開始する
+// イベントの監視はpageのコンテキストが終了するまで続く
+func setupConsoleCapture(page *rod.Page) (*consoleCollector, error) {
+	cc := &consoleCollector{}
+
+	// 有効化より前に監視を始め、有効化時に送られる既存のメッセージも受け取る
+	go page.EachEvent(func(e *proto.RuntimeConsoleAPICalled) {
+		msg := ConsoleMessage{
+			Level:     consoleLevel(string(e.Type)),
+			Source:    ConsoleSourceConsoleAPI,
+			Text:      formatConsoleArgs(e.Args),
+			Timestamp: runtimeTime(e.Timestamp),
+		}
+		if e.StackTrace != nil && len(e.StackTrace.CallFrames) > 0 {
+			f := e.StackTrace.CallFrames[0]
+			msg.URL, msg.Line, msg.Column = f.URL, f.LineNumber+1, f.ColumnNumber+1
+		}
+		cc.add(msg)
+	}, func(e *proto.RuntimeExceptionThrown) {
+		d := e.ExceptionDetails
+		msg := ConsoleMessage{
+			Level:     ConsoleLevelError,
+			Source:    ConsoleSourceException,
+			Text:      exceptionText(d),
+			URL:       d.URL,
+			Line:      d.LineNumber + 1,
+			Column:    d.ColumnNumber + 1,
+			Timestamp: runtimeTime(e.Timestamp),
+		}
+		if msg.URL == "" && d.StackTrace != nil && len(d.StackTrace.CallFrames) > 0 {
+			f := d.StackTrace.CallFrames[0]
+			msg.URL, msg.Line, msg.Column = f.URL, f.LineNumber+1, f.ColumnNumber+1
+		}
+		cc.add(msg)
+	}, func(e *proto.LogEntryAdded) {
+		entry := e.Entry
+		msg := ConsoleMessage{
+			Level:     consoleLevel(string(entry.Level)),
+			Source:    string(entry.Source),
+			Text:      entry.Text,
+			URL:       entry.URL,
+			Timestamp: runtimeTime(entry.Timestamp),
+		}
+		if entry.LineNumber != nil {
+			msg.Line = *entry.LineNumber + 1
+		}
+		cc.add(msg)
+	})()
+
+	if err := (proto.RuntimeEnable{}).Call(page); err != nil {
+		return nil, err
+	}
+	if err := (proto.LogEnable{}).Call(page); err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+// add はメッセージを記録する
+func (cc *consoleCollector) add(msg ConsoleMessage) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.messages = append(cc.messages, msg)
+}
+
+// getMessages は記録したメッセージを発生順に返す
+func (cc *consoleCollector) getMessages() []ConsoleMessage {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return append([]ConsoleMessage(nil), cc.messages...)
+}
+
+// consoleLevel はCDPのメッセージ種別をログレベルにまとめる
+// console.warn は"warning"、console.log・console.dir等は"log"として扱う
+func consoleLevel(t string) string {
+	switch t {
+	case "error", "assert":
+		return ConsoleLevelError
+	case "warning":
+		return ConsoleLevelWarning
+	case "info":
+		return ConsoleLevelInfo
+	case "debug", "verbose":
+		return ConsoleLevelDebug
+	default:
+		return ConsoleLevelLog
+	}
+}
+
+// formatConsoleArgs はconsole.log等の引数をブラウザの表示に近い形の文字列にする
+func formatConsoleArgs(args []*proto.RuntimeRemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		parts = append(parts, formatRemoteObject(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatRemoteObject はJSの値を文字列にする
+// 文字列はそのまま、数値等のプリミティブはJSON表記、オブジェクトはDescription（例: "Array(3)"）を使う
+func formatRemoteObject(obj *proto.RuntimeRemoteObject) string {
+	switch {
+	case obj.Type == proto.RuntimeRemoteObjectTypeString:
+		return obj.Value.Str()
+	case obj.UnserializableValue != "":
+		return string(obj.UnserializableValue)
+	case obj.Description != "":
+		return obj.Description
+	case !obj.Value.Nil():
+		return obj.Value.JSON("", "")
+	default:
+		return string(obj.Type)
+	}
+}
+
+// exceptionText は例外のメッセージを"Uncaught Error: message"の形にする
+// 例外オブジェクトのDescriptionにはスタックトレースが含まれるため1行目のみを使う
+func exceptionText(d *proto.RuntimeExceptionDetails) string {
+	if d.Exception == nil {
+		return d.Text
+	}
+	desc := formatRemoteObject(d.Exception)
+	if i := strings.IndexByte(desc, '\n'); i >= 0 {
+		desc = desc[:i]
+	}
+	if d.Text == "" {
+		return desc
+	}
+	return d.Text + " " + desc
+}
+
+// runtimeTime はCDPのタイムスタンプ（エポックからのミリ秒）を変換する
+func runtimeTime(ts proto.RuntimeTimestamp) time.Time {
+	return time.UnixMicro(int64(float64(ts) * 1000))
+}
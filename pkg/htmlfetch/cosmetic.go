@@ -0,0 +1,183 @@
+package htmlfetch
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// cosmeticFilter は要素隠しフィルタ（"example.com,~sub.example.com##.ad"）
+type cosmeticFilter struct {
+	selector   string
+	domains    []string
+	notDomains []string
+}
+
+// unsupportedSelectors はquerySelectorAllで使えない拡張構文（uBlock Origin・AdGuardの手続き的フィルタ等）
+var unsupportedSelectors = []string{
+	":-abp-", ":has-text(", ":xpath(", ":upward(", ":matches-css", ":style(", ":remove(",
+	":min-text-length(", ":watch-attr(", ":nth-ancestor(", ":matches-path(", ":contains(",
+}
+
+// addCosmetic は要素隠しフィルタ（##）と例外フィルタ（#@#）を追加する
+// スクリプトレット（##+js）・HTMLフィルタ（##^）・拡張構文は読み飛ばす
+func (fl *filterList) addCosmetic(line string) {
+	var domainPart, selector string
+	exception := false
+	if i := strings.Index(line, "#@#"); i >= 0 {
+		domainPart, selector, exception = line[:i], line[i+3:], true
+	} else if i := strings.Index(line, "##"); i >= 0 {
+		domainPart, selector = line[:i], line[i+2:]
+	} else {
+		return
+	}
+
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.HasPrefix(selector, "+js(") || strings.HasPrefix(selector, "^") {
+		return
+	}
+	for _, s := range unsupportedSelectors {
+		if strings.Contains(selector, s) {
+			return
+		}
+	}
+
+	f := &cosmeticFilter{selector: selector}
+	for _, d := range strings.Split(strings.ToLower(domainPart), ",") {
+		d = strings.TrimSpace(d)
+		if strings.HasPrefix(d, "~") {
+			f.notDomains = append(f.notDomains, d[1:])
+		} else if d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+
+	switch {
+	case exception:
+		fl.unhide[selector] = append(fl.unhide[selector], f)
+	case len(f.domains) == 0:
+		fl.hideGeneric = append(fl.hideGeneric, f)
+	default:
+		for _, d := range f.domains {
+			fl.hideByDomain[d] = append(fl.hideByDomain[d], f)
+		}
+	}
+}
+
+// cosmeticSelectors はページに適用する要素隠しフィルタのセレクタを返す
+func (fl *filterList) cosmeticSelectors(pageURL string) []string {
+	doc := newFilterRequest("", pageURL, pageURL)
+	for _, r := range fl.elemhide {
+		if r.matchURL(doc) {
+			return nil
+		}
+	}
+	generic := true
+	for _, r := range fl.generichide {
+		if r.matchURL(doc) {
+			generic = false
+			break
+		}
+	}
+
+	host := doc.host
+	var selectors []string
+	add := func(f *cosmeticFilter) {
+		if len(f.notDomains) > 0 && matchDomain(host, f.notDomains) {
+			return
+		}
+		for _, u := range fl.unhide[f.selector] {
+			if len(u.domains) == 0 && !matchDomain(host, u.notDomains) || matchDomain(host, u.domains) {
+				return
+			}
+		}
+		selectors = append(selectors, f.selector)
+	}
+
+	if generic {
+		for _, f := range fl.hideGeneric {
+			add(f)
+		}
+	}
+	// ホスト名とその親ドメイン（a.example.com → example.com → com）
+	for d := host; d != ""; {
+		for _, f := range fl.hideByDomain[d] {
+			add(f)
+		}
+		i := strings.IndexByte(d, '.')
+		if i < 0 {
+			break
+		}
+		d = d[i+1:]
+	}
+	return selectors
+}
+
+// cosmeticSelectors は組み込みのセレクタ・CosmeticRule・フィルタリストから、ページに適用するセレクタを返す
+func cosmeticSelectors(pageURL string, rules []CosmeticRule, filters *filterList) []string {
+	seen := make(map[string]bool)
+	var selectors []string
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			selectors = append(selectors, s)
+		}
+	}
+
+	for _, s := range builtinCosmeticSelectors {
+		add(s)
+	}
+	host := hostOf(pageURL)
+	for _, r := range rules {
+		if len(r.Domains) == 0 || matchDomain(host, normalizeDomains(r.Domains)) {
+			add(r.Selector)
+		}
+	}
+	if filters != nil {
+		for _, s := range filters.cosmeticSelectors(pageURL) {
+			add(s)
+		}
+	}
+	return selectors
+}
+
+// removeCosmeticElements はセレクタに一致する要素をDOMから削除し、削除した要素数を返す
+// セレクタはまとめて照合し、不正なセレクタを含むまとまりは1つずつ照合し直す
+func removeCosmeticElements(page *rod.Page, selectors []string) (int, error) {
+	if len(selectors) == 0 {
+		return 0, nil
+	}
+	res, err := page.Eval(`(selectors) => {
+		const chunkSize = 200;
+		const targets = new Set();
+		const collect = (sel) => {
+			try {
+				document.querySelectorAll(sel).forEach(el => targets.add(el));
+				return true;
+			} catch (e) {
+				return false;
+			}
+		};
+		for (let i = 0; i < selectors.length; i += chunkSize) {
+			const chunk = selectors.slice(i, i + chunkSize);
+			if (!collect(chunk.join(','))) {
+				chunk.forEach(collect);
+			}
+		}
+		// <html>・<body>自体は削除しない
+		targets.delete(document.documentElement);
+		targets.delete(document.body);
+		let removed = 0;
+		targets.forEach(el => {
+			if (el.isConnected) {
+				el.remove();
+				removed++;
+			}
+		});
+		return removed;
+	}`, selectors)
+	if err != nil {
+		return 0, err
+	}
+	return res.Value.Int(), nil
+}
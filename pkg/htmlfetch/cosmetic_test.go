@@ -0,0 +1,66 @@
+package htmlfetch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterList_CosmeticSelectors(t *testing.T) {
+	fl := newFilterList()
+	list := `##.ad-banner
+##.sponsored
+news.example.jp##.pr-box
+example.com,~shop.example.com##.side-ad
+~blog.example.com##.footer-ad
+blog.example.com#@#.sponsored
+#@#.legacy-ad
+##.legacy-ad
+example.com##+js(set-constant, ads, false)
+example.com##.promo:has-text(広告)
+@@||nohide.example.org^$elemhide
+@@||generic.example.org^$generichide
+generic.example.org##.own-ad
+`
+	if err := fl.parse(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://www.example.net/", []string{".ad-banner", ".sponsored", ".footer-ad"}},
+		{"https://news.example.jp/a", []string{".ad-banner", ".sponsored", ".footer-ad", ".pr-box"}},
+		{"https://www.example.com/", []string{".ad-banner", ".sponsored", ".footer-ad", ".side-ad"}},
+		{"https://shop.example.com/", []string{".ad-banner", ".sponsored", ".footer-ad"}},
+		{"https://blog.example.com/", []string{".ad-banner", ".side-ad"}},
+		{"https://nohide.example.org/", nil},
+		{"https://generic.example.org/", []string{".own-ad"}},
+	}
+	for _, tt := range tests {
+		if got := fl.cosmeticSelectors(tt.url); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cosmeticSelectors(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCosmeticSelectors(t *testing.T) {
+	fl := newFilterList()
+	fl.addLine("example.com##.list-ad")
+	rules := []CosmeticRule{
+		{Selector: ".global"},
+		{Selector: ".only-example", Domains: []string{"example.com"}},
+		{Selector: ".only-other", Domains: []string{"other.example"}},
+		{Selector: "ins.adsbygoogle"}, // 組み込みのセレクタと重複
+	}
+
+	got := cosmeticSelectors("https://www.example.com/", rules, fl)
+	if len(got) != len(builtinCosmeticSelectors)+3 {
+		t.Fatalf("セレクタ数: got %d", len(got))
+	}
+	tail := got[len(builtinCosmeticSelectors):]
+	if want := []string{".global", ".only-example", ".list-ad"}; !reflect.DeepEqual(tail, want) {
+		t.Errorf("got %q, want %q", tail, want)
+	}
+}
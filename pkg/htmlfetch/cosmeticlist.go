@@ -0,0 +1,54 @@
+package htmlfetch
+
+// builtinCosmeticSelectors はWithCosmeticFiltering指定時に常に削除する要素のセレクタ
+// 主要な同意管理プラットフォーム（CMP）のCookieバナーと、国内で多い広告枠のコンテナ
+var builtinCosmeticSelectors = []string{
+	// Cookie・同意バナー
+	"#onetrust-consent-sdk",
+	"#onetrust-banner-sdk",
+	"#CybotCookiebotDialog",
+	"#usercentrics-root",
+	"#didomi-host",
+	"#truste-consent-track",
+	".qc-cmp2-container",
+	".fc-consent-root",
+	"[id^=\"sp_message_container_\"]",
+	"#cmpbox",
+	"#cmpbox2",
+	".osano-cm-window",
+	".cky-consent-container",
+	"#cookie-law-info-bar",
+	"#cookie-notice",
+	"#gdpr-cookie-message",
+	".cc-window.cc-banner",
+	"#moove_gdpr_cookie_info_bar",
+	"#BorlabsCookieBox",
+	".iubenda-cs-container",
+
+	// Google AdSense・Google Ad Manager
+	"ins.adsbygoogle",
+	"[id^=\"div-gpt-ad\"]",
+	"[id^=\"google_ads_iframe\"]",
+
+	// Yahoo!広告（YDN）
+	".yads_ad",
+	".yads_ad_res",
+	"[id^=\"yads_\"]",
+	".ydn-ad",
+
+	// 国内アドネットワーク
+	"[id^=\"gmossp_\"]",
+	".gmossp_core",
+	"[class^=\"fluct-unit\"]",
+	"[id^=\"microad\"]",
+	".microadcompass",
+	"[id^=\"i-mobile\"]",
+	"[id^=\"im-\"][id$=\"-ad\"]",
+	".nend_wrapper",
+	".adingo",
+	"[id^=\"adgsp\"]",
+	"[id^=\"zucks\"]",
+	"[id^=\"logly-lift-\"]",
+	".uz-lift-ad",
+	"[id^=\"_popIn_recommend\"]",
+}
@@ -0,0 +1,206 @@
+package htmlfetch
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+)
+
+// Device はエミュレートするデバイスの設定
+type Device struct {
+	Name              string
+	Width             int
+	Height            int
+	DeviceScaleFactor float64
+	Mobile            bool
+	Touch             bool
+	UserAgent         string // 空の場合はブラウザのUAを使用。{version}はChromeのバージョンに置換される
+	Platform          string // navigator.platform（空の場合は変更しない）
+}
+
+// Geolocation はエミュレートする位置情報
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64 // メートル
+}
+
+// 組み込みのデバイスプリセット
+var (
+	DeviceIPhone = Device{
+		Name:              "iPhone 15",
+		Width:             393,
+		Height:            852,
+		DeviceScaleFactor: 3,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		Platform:          "iPhone",
+	}
+	DevicePixel = Device{
+		Name:              "Pixel 8",
+		Width:             412,
+		Height:            915,
+		DeviceScaleFactor: 2.625,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/{version} Mobile Safari/537.36",
+		Platform:          "Linux armv81",
+	}
+	DeviceIPad = Device{
+		Name:              "iPad Air",
+		Width:             820,
+		Height:            1180,
+		DeviceScaleFactor: 2,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		Platform:          "iPad",
+	}
+	DeviceDesktopHiDPI = Device{
+		Name:              "Desktop HiDPI",
+		Width:             1440,
+		Height:            900,
+		DeviceScaleFactor: 2,
+	}
+)
+
+// devices はLookupDeviceで名前から引けるプリセット
+var devices = map[string]Device{
+	"iphone":        DeviceIPhone,
+	"pixel":         DevicePixel,
+	"ipad":          DeviceIPad,
+	"desktop-hidpi": DeviceDesktopHiDPI,
+}
+
+// LookupDevice は名前（iphone/pixel/ipad/desktop-hidpi）から組み込みデバイスを返す
+func LookupDevice(name string) (Device, bool) {
+	d, ok := devices[strings.ToLower(name)]
+	return d, ok
+}
+
+// emulateDevice はデバイスの画面サイズ・解像度・タッチ操作をエミュレートする
+func emulateDevice(page *rod.Page, d Device) error {
+	scale := d.DeviceScaleFactor
+	if scale == 0 {
+		scale = 1
+	}
+	err := page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
+		Width:             d.Width,
+		Height:            d.Height,
+		DeviceScaleFactor: scale,
+		Mobile:            d.Mobile,
+	})
+	if err != nil {
+		return err
+	}
+
+	touch := proto.EmulationSetTouchEmulationEnabled{Enabled: d.Touch}
+	if d.Touch {
+		touch.MaxTouchPoints = gson.Int(5)
+	}
+	return touch.Call(page)
+}
+
+// defaultUserAgent はWithUserAgentを指定しない場合のUAを返す
+// デバイスのUAがあればそれを使い、なければブラウザのUAからHeadlessChrome表記を除去する
+// ブラウザのバージョンを取得できなかった場合（verがnil）、デバイスのUAは使い、ブラウザのUAは空（変更しない）にする
+func defaultUserAgent(d Device, ver *proto.BrowserGetVersionResult) string {
+	if d.UserAgent != "" {
+		var product string
+		if ver != nil {
+			product = ver.Product
+		}
+		return deviceUserAgent(d, product)
+	}
+	if ver == nil {
+		return ""
+	}
+	return strings.Replace(ver.UserAgent, "HeadlessChrome", "Chrome", 1)
+}
+
+// fallbackChromeVersion はブラウザのバージョンを取得できない場合にデバイスのUAに埋め込むバージョン
+// 0.0.0.0のような実在しないバージョンはbot判定の手がかりになるため、実在する安定版のバージョンを固定で使う
+const fallbackChromeVersion = "131.0.0.0"
+
+// deviceUserAgent はデバイスのUAテンプレートにブラウザのChromeバージョンを埋め込む
+// UA削減（User-Agent Reduction）に合わせてメジャーバージョン以外は0にする
+// バージョンが不明な場合はfallbackChromeVersionを埋め込む
+func deviceUserAgent(d Device, product string) string {
+	if !strings.Contains(d.UserAgent, "{version}") {
+		return d.UserAgent
+	}
+	version := fallbackChromeVersion
+	if _, v, ok := strings.Cut(product, "/"); ok {
+		if major, _, _ := strings.Cut(v, "."); major != "" {
+			version = major + ".0.0.0"
+		}
+	}
+	return strings.ReplaceAll(d.UserAgent, "{version}", version)
+}
+
+// emulateEnvironment はタイムゾーン・ロケール・位置情報をエミュレートする
+// 位置情報の権限は事前にgrantGeolocationで付与しておく
+func emulateEnvironment(page *rod.Page, cfg *fetchConfig) error {
+	if cfg.timezone != "" {
+		if err := (proto.EmulationSetTimezoneOverride{TimezoneID: cfg.timezone}).Call(page); err != nil {
+			return err
+		}
+	}
+
+	if cfg.locale != "" {
+		if err := (proto.EmulationSetLocaleOverride{Locale: cfg.locale}).Call(page); err != nil {
+			return err
+		}
+	}
+
+	if geo := cfg.geolocation; geo != nil {
+		err := proto.EmulationSetGeolocationOverride{
+			Latitude:  &geo.Latitude,
+			Longitude: &geo.Longitude,
+			Accuracy:  &geo.Accuracy,
+		}.Call(page)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantGeolocation はブラウザコンテキストにgeolocationの権限を付与し、取り消す関数を返す
+// 権限はコンテキスト全体に付与されるため、同じブラウザでWithGeolocationを指定した
+// Fetchを数え、最後のFetchが終わったときに取り消す
+// コールドモードではFetchごとに別のブラウザを起動するため、ブラウザのインスタンスごとに数える
+// （BrowserContextIDはどのブラウザでもデフォルトコンテキストの""になる）
+func (f *Fetcher) grantGeolocation(browser *rod.Browser) (func(), error) {
+	id := browser.BrowserContextID
+
+	f.geoMu.Lock()
+	defer f.geoMu.Unlock()
+	if f.geoGrants[browser] == 0 {
+		err := proto.BrowserGrantPermissions{
+			Permissions:      []proto.BrowserPermissionType{proto.BrowserPermissionTypeGeolocation},
+			BrowserContextID: id,
+		}.Call(browser)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.geoGrants == nil {
+		f.geoGrants = make(map[*rod.Browser]int)
+	}
+	f.geoGrants[browser]++
+
+	return func() {
+		f.geoMu.Lock()
+		defer f.geoMu.Unlock()
+		f.geoGrants[browser]--
+		if f.geoGrants[browser] > 0 {
+			return
+		}
+		delete(f.geoGrants, browser)
+		_ = proto.BrowserResetPermissions{BrowserContextID: id}.Call(browser)
+	}, nil
+}
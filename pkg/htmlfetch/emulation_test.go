@@ -0,0 +1,39 @@
+package htmlfetch
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestDefaultUserAgent(t *testing.T) {
+	ver := &proto.BrowserGetVersionResult{
+		Product:   "HeadlessChrome/126.0.6478.126",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/126.0.6478.126 Safari/537.36",
+	}
+	tests := []struct {
+		name   string
+		device Device
+		ver    *proto.BrowserGetVersionResult
+		want   string
+	}{
+		{"browser", Device{}, ver,
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.126 Safari/537.36"},
+		{"browser_unknown", Device{}, nil, ""},
+		{"device_version", DevicePixel, ver,
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36"},
+		// バージョンを取得できなくても、実在するバージョンでデバイスのUAを使う
+		{"device_version_unknown", DevicePixel, nil,
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/" + fallbackChromeVersion + " Mobile Safari/537.36"},
+		{"device_version_empty", DevicePixel, &proto.BrowserGetVersionResult{Product: "HeadlessChrome/"},
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/" + fallbackChromeVersion + " Mobile Safari/537.36"},
+		{"device_fixed_unknown", DeviceIPhone, nil, DeviceIPhone.UserAgent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultUserAgent(tt.device, tt.ver); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
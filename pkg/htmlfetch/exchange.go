@@ -0,0 +1,132 @@
+package htmlfetch
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// exchange は1回のHTTPリクエストとレスポンスの記録
+// リダイレクトはリダイレクトごとに別のexchangeとして記録する
+type exchange struct {
+	requestID      proto.NetworkRequestID
+	url            string
+	method         string
+	requestHeaders proto.NetworkHeaders
+	postData       string
+	resourceType   string
+	wallTime       time.Time
+	startTime      proto.MonotonicTime
+	endTime        proto.MonotonicTime
+	response       *proto.NetworkResponse
+	redirected     bool   // リダイレクトレスポンス（ボディなし）
+	finished       bool   // レスポンスの受信が完了した
+	errorText      string // 読み込みに失敗した場合のエラー
+	blockedReason  string
+	blockedBy      string // ブロッキング設定でブロックしたルール（NetworkStats.BlockedByRuleのキー）
+	canceled       bool
+	dataLength     int64   // 展開後のボディサイズ
+	encodedLength  float64 // 転送サイズ（ヘッダーを含む）
+	body           []byte
+	bodyLoaded     bool
+
+	// ExtraInfoイベントから求めたヘッダーのサイズ（不明な場合は0）
+	requestHeadersSize  int64
+	responseHeadersSize int64
+}
+
+// recordRequest はリクエストの送信を記録する
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) recordRequest(e *proto.NetworkRequestWillBeSent) {
+	if prev, ok := sc.pending[e.RequestID]; ok && e.RedirectResponse != nil {
+		prev.response = e.RedirectResponse
+		prev.redirected = true
+		prev.finished = true
+		prev.endTime = e.Timestamp
+		prev.encodedLength = e.RedirectResponse.EncodedDataLength
+	}
+
+	ex := &exchange{
+		requestID:      e.RequestID,
+		url:            e.Request.URL,
+		method:         e.Request.Method,
+		requestHeaders: e.Request.Headers,
+		postData:       e.Request.PostData,
+		resourceType:   string(e.Type),
+		wallTime:       e.WallTime.Time(),
+		startTime:      e.Timestamp,
+	}
+	sc.exchanges = append(sc.exchanges, ex)
+	sc.pending[e.RequestID] = ex
+}
+
+// loadResponseBodies は受信が完了したレスポンスのボディをブラウザから取得する
+// ページを閉じる・遷移する前に呼ぶこと
+func (sc *statsCollector) loadResponseBodies(page *rod.Page) {
+	for _, ex := range sc.getExchanges() {
+		sc.loadResponseBody(page, ex)
+	}
+}
+
+// loadResponseBody は1つのレスポンスのボディをブラウザから取得する
+// 取得済み・取得できないレスポンスは何もしない
+func (sc *statsCollector) loadResponseBody(page *rod.Page, ex *exchange) {
+	sc.mu.Lock()
+	skip := ex.bodyLoaded || !ex.finished || ex.redirected || ex.response == nil || !isFetchableURL(ex.url)
+	sc.mu.Unlock()
+	if skip {
+		return
+	}
+
+	res, err := proto.NetworkGetResponseBody{RequestID: ex.requestID}.Call(page)
+	if err != nil {
+		return
+	}
+
+	body := []byte(res.Body)
+	if res.Base64Encoded {
+		if body, err = base64.StdEncoding.DecodeString(res.Body); err != nil {
+			return
+		}
+	}
+
+	sc.mu.Lock()
+	ex.body = body
+	ex.bodyLoaded = true
+	sc.mu.Unlock()
+}
+
+// getExchanges は記録したリクエストを発生順に返す
+func (sc *statsCollector) getExchanges() []*exchange {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return append([]*exchange(nil), sc.exchanges...)
+}
+
+// snapshotExchanges は記録したリクエストのコピーを発生順に返す
+// イベントのgoroutineはFetchの終了まで記録を更新するため、書き出しにはこのコピーを使う
+func (sc *statsCollector) snapshotExchanges() []*exchange {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	exchanges := make([]*exchange, len(sc.exchanges))
+	for i, ex := range sc.exchanges {
+		c := *ex
+		exchanges[i] = &c
+	}
+	return exchanges
+}
+
+// headerLines はCDPのヘッダーを「名前, 値」の組に展開する
+// CDPは同名の複数ヘッダー（Set-Cookie等）を改行区切りで1つにまとめるため分割する
+func headerLines(headers proto.NetworkHeaders) [][2]string {
+	var lines [][2]string
+	for name, value := range headers {
+		for _, v := range strings.Split(value.Str(), "\n") {
+			lines = append(lines, [2]string{name, v})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,60 @@
+package htmlfetch
+
+import "strings"
+
+// リクエストの失敗理由
+const (
+	FailureBlocked    = "blocked"    // ブロッキング設定・CSP等によるブロック
+	FailureDNS        = "dns"        // 名前解決の失敗
+	FailureTLS        = "tls"        // 証明書・TLSハンドシェイクのエラー
+	FailureTimeout    = "timeout"    // 接続・応答のタイムアウト
+	FailureCORS       = "cors"       // CORSによる拒否
+	FailureConnection = "connection" // 接続の拒否・切断
+	FailureCanceled   = "canceled"   // ページ遷移等によるキャンセル
+	FailureOther      = "other"
+)
+
+// NetworkStats.BlockedByRuleのキー
+// リソースタイプによるブロックはリソースタイプ名（"Image"等）、WithBlockRulesのルールはBlockRule.Nameをキーにする
+const (
+	BlockRuleAds    = "ads"    // 広告ドメイン・WithFilterListsのフィルタリストによるブロック
+	BlockRuleCustom = "custom" // Nameを指定していないBlockRuleによるブロック
+)
+
+// typicalResourceSize はブロックで削減できた受信量を推定する際の1リクエストあたりの概算サイズ
+// 同じFetch内で同じリソースタイプの受信実績がない場合に使う
+var typicalResourceSize = map[string]int64{
+	"Document":   30 << 10,
+	"Stylesheet": 10 << 10,
+	"Script":     20 << 10,
+	"Image":      15 << 10,
+	"Font":       30 << 10,
+	"Media":      200 << 10,
+	"XHR":        2 << 10,
+	"Fetch":      2 << 10,
+}
+
+// classifyFailure はNetwork.loadingFailedの内容から失敗理由を分類する
+// errorTextはChromiumのネットエラー名（例: "net::ERR_NAME_NOT_RESOLVED"）
+func classifyFailure(errorText string, canceled bool, blockedReason string, corsError bool) string {
+	name := strings.TrimPrefix(errorText, "net::")
+	switch {
+	case blockedReason != "" || strings.HasPrefix(name, "ERR_BLOCKED_BY_CLIENT"):
+		return FailureBlocked
+	case corsError:
+		return FailureCORS
+	case canceled || name == "ERR_ABORTED":
+		return FailureCanceled
+	case name == "ERR_NAME_NOT_RESOLVED" || name == "ERR_NAME_RESOLUTION_FAILED" || strings.HasPrefix(name, "ERR_DNS_"):
+		return FailureDNS
+	case strings.HasPrefix(name, "ERR_CERT_") || strings.HasPrefix(name, "ERR_SSL_") || strings.Contains(name, "_SSL_"):
+		return FailureTLS
+	case strings.HasSuffix(name, "TIMED_OUT"):
+		return FailureTimeout
+	case strings.HasPrefix(name, "ERR_CONNECTION_") || name == "ERR_ADDRESS_UNREACHABLE" ||
+		name == "ERR_EMPTY_RESPONSE" || name == "ERR_INTERNET_DISCONNECTED":
+		return FailureConnection
+	default:
+		return FailureOther
+	}
+}
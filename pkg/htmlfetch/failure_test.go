@@ -0,0 +1,36 @@
+package htmlfetch
+
+import "testing"
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		errorText     string
+		canceled      bool
+		blockedReason string
+		corsError     bool
+		want          string
+	}{
+		{"net::ERR_BLOCKED_BY_CLIENT", false, "", false, FailureBlocked},
+		{"net::ERR_BLOCKED_BY_CLIENT.Inspector", false, "", false, FailureBlocked},
+		{"net::ERR_BLOCKED_BY_RESPONSE", false, "csp", false, FailureBlocked},
+		{"net::ERR_FAILED", false, "", true, FailureCORS},
+		{"net::ERR_ABORTED", false, "", false, FailureCanceled},
+		{"net::ERR_FAILED", true, "", false, FailureCanceled},
+		{"net::ERR_NAME_NOT_RESOLVED", false, "", false, FailureDNS},
+		{"net::ERR_CERT_DATE_INVALID", false, "", false, FailureTLS},
+		{"net::ERR_SSL_PROTOCOL_ERROR", false, "", false, FailureTLS},
+		{"net::ERR_BAD_SSL_CLIENT_AUTH_CERT", false, "", false, FailureTLS},
+		{"net::ERR_TIMED_OUT", false, "", false, FailureTimeout},
+		{"net::ERR_CONNECTION_TIMED_OUT", false, "", false, FailureTimeout},
+		{"net::ERR_CONNECTION_REFUSED", false, "", false, FailureConnection},
+		{"net::ERR_EMPTY_RESPONSE", false, "", false, FailureConnection},
+		{"net::ERR_INVALID_RESPONSE", false, "", false, FailureOther},
+	}
+	for _, tt := range tests {
+		got := classifyFailure(tt.errorText, tt.canceled, tt.blockedReason, tt.corsError)
+		if got != tt.want {
+			t.Errorf("classifyFailure(%q, %v, %q, %v) = %q, want %q",
+				tt.errorText, tt.canceled, tt.blockedReason, tt.corsError, got, tt.want)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,14 +11,26 @@ import (
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
 	"github.com/go-rod/stealth"
+	"github.com/ysmood/gson"
 )
 
 // Fetcher はrod/Chromiumを使ったHTMLフェッチャー
 type Fetcher struct {
-	config  fetcherConfig
-	browser *rod.Browser
-	mu      sync.Mutex
-	started bool
+	config   fetcherConfig
+	browser  *rod.Browser
+	launcher *launcher.Launcher
+	pages    *pagePool
+	mu       sync.Mutex
+	started  bool
+
+	// WithGeolocationで権限を付与しているFetchの数（ブラウザごと）
+	geoMu     sync.Mutex
+	geoGrants map[*rod.Browser]int
+
+	// WithFilterListsのフィルタリスト（最初に必要になった時に1度だけ読み込む）
+	filtersOnce sync.Once
+	filters     *filterList
+	filtersErr  error
 }
 
 // New は新しいFetcherを作成
@@ -44,12 +55,22 @@ func (f *Fetcher) Start() error {
 		return nil
 	}
 
-	browser, err := f.launchBrowser()
+	if _, err := f.filterList(); err != nil {
+		return err
+	}
+
+	browser, l, err := f.launchBrowserProcess()
 	if err != nil {
 		return err
 	}
 
 	f.browser = browser
+	f.launcher = l
+	if f.config.maxPages > 0 {
+		f.pages = newPagePool(f.config.maxPages, func() (*rod.Page, error) {
+			return f.createPage(browser)
+		})
+	}
 	f.started = true
 	return nil
 }
@@ -64,8 +85,18 @@ func (f *Fetcher) Close() error {
 		return nil
 	}
 
+	if f.pages != nil {
+		f.pages.close()
+		f.pages = nil
+	}
 	err := f.browser.Close()
+	// 応答しないブラウザも確実に終了させる
+	if f.launcher != nil {
+		f.launcher.Kill()
+		f.launcher.Cleanup()
+	}
 	f.browser = nil
+	f.launcher = nil
 	f.started = false
 	return err
 }
@@ -80,8 +111,12 @@ func (f *Fetcher) IsStarted() bool {
 // Fetch はURLからHTMLを取得
 // Start()が呼ばれていない場合: 毎回ブラウザを起動→取得→終了（従来動作）
 // Start()が呼ばれている場合: タブを作成→取得→閉じる（高速モード）
+// WithMaxConcurrentPages指定時: 空きタブを待って再利用する（プールモード）
 func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*Result, error) {
 	startTime := time.Now()
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
 	// Fetch設定を構築
 	cfg := &fetchConfig{}
@@ -90,45 +125,116 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*
 	}
 	applyDefaults(cfg)
 
+	filters, err := f.filterList()
+	if err != nil {
+		return nil, err
+	}
+
 	// ブラウザとページを取得
-	browser, page, cleanup, err := f.getBrowserAndPage()
+	browser, page, cleanup, err := f.getBrowserAndPage(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
-	// ブロッキングセットを作成
-	blockSet := newBlockingSet(cfg.blocking)
+	// イベント監視をこのFetch内に限定する（プールモードでタブを再利用するため）
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	page = page.Context(fetchCtx)
+
+	// ブロッキングの判定を作成
+	blocker := newBlocker(cfg.blocking, cfg.blockRules, filters)
 
 	// ネットワーク統計収集を設定
-	collector := newStatsCollector(blockSet)
+	collector := newStatsCollector(blocker)
+	collector.recordExchanges = cfg.warc != nil || cfg.har || len(cfg.captureFilters) > 0
 	collector.setupNetworkStats(page)
 
+	// コンソール出力の収集を設定（オプション）
+	var console *consoleCollector
+	if cfg.console {
+		if console, err = setupConsoleCapture(page); err != nil {
+			return nil, &FetchError{
+				Code:    ErrInternalError,
+				Message: "コンソール出力の収集の設定に失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
 	// リソースブロッキングを設定
-	setupFetchBlocking(page, blockSet, cfg.blocking.Ads)
+	setupFetchBlocking(page, blocker)
 
-	// ビューポートを設定
-	page.MustSetViewport(cfg.viewportWidth, cfg.viewportHeight, 1, false)
+	// パフォーマンス指標の収集を開始（Performance.getMetricsは有効化後の値のみ返す）
+	_ = proto.PerformanceEnable{}.Call(page)
+
+	// ビューポートを設定（ブラウザが落ちていてもパニックしないようエラーは無視）
+	device := Device{Width: cfg.viewportWidth, Height: cfg.viewportHeight}
+	if cfg.device != nil {
+		device = *cfg.device
+	}
+	_ = emulateDevice(page, device)
 
 	// タイムアウトを設定（最大60秒）
 	page = page.Timeout(60 * time.Second)
 
-	// コンテキストを適用
-	if ctx != nil {
-		page = page.Context(ctx)
-	}
-
-	// Accept-Languageを日本語優先に設定し、UserAgentからHeadlessChrome表記を除去
-	ua := ""
-	if ver, verErr := (proto.BrowserGetVersion{}).Call(browser); verErr == nil {
-		ua = strings.Replace(ver.UserAgent, "HeadlessChrome", "Chrome", 1)
+	// Accept-Languageを設定し（デフォルト: 日本語優先）、UserAgentからHeadlessChrome表記を除去
+	// UAの優先順位: WithUserAgent > デバイスのUA > ブラウザのUA
+	ua := cfg.userAgent
+	if ua == "" {
+		var ver *proto.BrowserGetVersionResult
+		if v, verErr := (proto.BrowserGetVersion{}).Call(browser); verErr == nil {
+			ver = v
+		}
+		ua = defaultUserAgent(device, ver)
 	}
 	page.SetUserAgent(&proto.NetworkSetUserAgentOverride{
 		UserAgent:      ua,
-		AcceptLanguage: "ja",
+		AcceptLanguage: cfg.acceptLanguage,
+		Platform:       device.Platform,
 	})
 
+	// タイムゾーン・ロケール・位置情報をエミュレート
+	// 位置情報の権限はFetchの終了時（タブを返す前）に取り消す
+	if cfg.geolocation != nil {
+		revoke, err := f.grantGeolocation(browser)
+		if err != nil {
+			return nil, &FetchError{
+				Code:    ErrInternalError,
+				Message: "タイムゾーン・ロケール・位置情報の設定に失敗しました",
+				Cause:   err,
+			}
+		}
+		defer revoke()
+	}
+	if err := emulateEnvironment(page, cfg); err != nil {
+		return nil, &FetchError{
+			Code:    ErrInternalError,
+			Message: "タイムゾーン・ロケール・位置情報の設定に失敗しました",
+			Cause:   err,
+		}
+	}
+
+	// 追加のリクエストヘッダーを設定
+	if len(cfg.headers) > 0 {
+		if err := setExtraHeaders(page, cfg.headers); err != nil {
+			return nil, &FetchError{
+				Code:    ErrInternalError,
+				Message: "リクエストヘッダーの設定に失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
+	// セッションのlocalStorageを復元
+	if cfg.session != nil {
+		_ = cfg.session.prepare(page)
+	}
+
 	// ページに遷移
+	var timing Timing
+	timing.Setup = time.Since(startTime)
+	phaseStart := time.Now()
 	if err := page.Navigate(url); err != nil {
 		return nil, &FetchError{
 			Code:    ErrNavigationFailed,
@@ -137,7 +243,10 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*
 		}
 	}
 
+	timing.Navigation = time.Since(phaseStart)
+
 	// 待機戦略に応じて待機
+	phaseStart = time.Now()
 	if err := waitForPage(page, cfg.waitStrategy); err != nil {
 		return nil, &FetchError{
 			Code:    ErrFetchTimeout,
@@ -146,8 +255,39 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*
 		}
 	}
 
+	timing.Wait = time.Since(phaseStart)
+
+	// Cookie同意ダイアログを処理（オプション）
+	var consent *ConsentResult
+	if cfg.consent != "" {
+		phaseStart = time.Now()
+		consent, _ = handleConsent(page, cfg.consent)
+		timing.Consent = time.Since(phaseStart)
+	}
+
+	// クリック・入力等の操作を実行（オプション）
+	if len(cfg.actions) > 0 {
+		phaseStart = time.Now()
+		if err := runActions(page, cfg.actions); err != nil {
+			return nil, &FetchError{
+				Code:    ErrActionFailed,
+				Message: "アクションの実行に失敗しました",
+				Cause:   err,
+			}
+		}
+		timing.Actions = time.Since(phaseStart)
+	}
+
+	// 無限スクロール・遅延読み込みの展開（オプション）
+	if cfg.autoScroll != nil {
+		phaseStart = time.Now()
+		_ = autoScroll(page, *cfg.autoScroll)
+		timing.AutoScroll = time.Since(phaseStart)
+	}
+
 	// セレクタ待機（オプション）
 	if cfg.selector != "" {
+		phaseStart = time.Now()
 		if err := waitForSelector(page, cfg.selector, cfg.selectorTimeout); err != nil {
 			return nil, &FetchError{
 				Code:    ErrSelectorNotFound,
@@ -155,21 +295,83 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*
 				Cause:   err,
 			}
 		}
+		timing.SelectorWait = time.Since(phaseStart)
 	}
 
 	// 少し待ってイベントを確実に収集
 	time.Sleep(100 * time.Millisecond)
 
+	// 変換処理でDOMが変わる前にパフォーマンス指標を取得
+	loadPageTiming(page, &timing)
+
+	// 広告枠・Cookieバナー等の要素を削除（オプション）
+	phaseStart = time.Now()
+	var removedElements int
+	if cfg.cosmetic {
+		var pageURL string
+		if info, err := page.Info(); err == nil {
+			pageURL = info.URL
+		}
+		removedElements, _ = removeCosmeticElements(page, cosmeticSelectors(pageURL, cfg.cosmeticRules, filters))
+	}
+
 	// CSS埋め込み（オプション）
 	if cfg.embedCSS {
 		_ = embedCSS(page)
 	}
 
+	// アセットのインライン化（オプション）
+	if cfg.inlineAssets {
+		_ = inlineAssets(page, cfg.inlineMaxAsset, cfg.inlineMaxTotal)
+	}
+
 	// スクリプト除去（オプション）
 	if cfg.stripScripts {
 		_ = stripScripts(page)
 	}
 
+	timing.Transform = time.Since(phaseStart)
+
+	// スクリーンショットを撮影（オプション）
+	phaseStart = time.Now()
+	var screenshot []byte
+	if cfg.screenshot != nil {
+		screenshot, err = captureScreenshot(page, *cfg.screenshot)
+		if err != nil {
+			return nil, &FetchError{
+				Code:    ErrCaptureFailed,
+				Message: "スクリーンショットの撮影に失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
+	// PDFを出力（オプション）
+	var pdf []byte
+	if cfg.pdf != nil {
+		pdf, err = printPDF(page, *cfg.pdf)
+		if err != nil {
+			return nil, &FetchError{
+				Code:    ErrCaptureFailed,
+				Message: "PDFの出力に失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
+	// MHTMLを保存（オプション）
+	var mhtml string
+	if cfg.mhtml {
+		mhtml, err = captureMHTML(page)
+		if err != nil {
+			return nil, &FetchError{
+				Code:    ErrCaptureFailed,
+				Message: "MHTMLの保存に失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
 	// HTMLを取得
 	html, err := page.HTML()
 	if err != nil {
@@ -180,39 +382,165 @@ func (f *Fetcher) Fetch(ctx context.Context, url string, opts ...FetchOption) (*
 		}
 	}
 
+	// セッションのlocalStorageを更新
+	if cfg.session != nil {
+		_ = cfg.session.capture(page)
+	}
+
 	// 最終URLを取得
 	finalURL := url
 	if info, err := page.Info(); err == nil {
 		finalURL = info.URL
 	}
 
+	// レスポンスのボディを記録（オプション）
+	var responses []CapturedResponse
+	if len(cfg.captureFilters) > 0 {
+		responses = captureResponses(page, collector, cfg.captureFilters)
+	}
+
+	// WARCを書き込み（オプション）
+	if cfg.warc != nil {
+		collector.loadResponseBodies(page)
+		if err := writeWARC(cfg.warc, collector.snapshotExchanges(), finalURL, html, time.Now()); err != nil {
+			return nil, &FetchError{
+				Code:    ErrCaptureFailed,
+				Message: "WARCの書き込みに失敗しました",
+				Cause:   err,
+			}
+		}
+	}
+
+	// HARを作成（オプション）
+	var har *HAR
+	if cfg.har {
+		har = buildHAR(collector.snapshotExchanges(), loadHARPageInfo(page, startTime))
+	}
+
+	timing.Capture = time.Since(phaseStart)
+
+	redirects, headers := collector.getRedirects()
 	result := &Result{
-		HTML:        html,
-		FinalURL:    finalURL,
-		StatusCode:  collector.getStatusCode(),
-		Stats:       collector.getStats(),
-		Duration:    time.Since(startTime),
+		HTML:       html,
+		Screenshot: screenshot,
+		PDF:        pdf,
+		MHTML:      mhtml,
+		HAR:        har,
+		Responses:  responses,
+		FinalURL:   finalURL,
+		StatusCode: collector.getStatusCode(),
+		Headers:    headers,
+		Redirects:  redirects,
+		Stats:      collector.getStats(),
+		Duration:   time.Since(startTime),
+		Timing:     timing,
+	}
+
+	if console != nil {
+		result.Console = console.getMessages()
 	}
+	result.RemovedElements = removedElements
+	result.Consent = consent
 
 	// Markdown変換（オプション）
 	if cfg.markdown {
+		phaseStart = time.Now()
 		markdown, err := convertToMarkdown(html, finalURL)
 		if err != nil {
 			return nil, err
 		}
 		result.Markdown = markdown
+		result.Timing.Markdown = time.Since(phaseStart)
 	}
 
 	return result, nil
 }
 
+// filterList はWithFilterListsで指定したフィルタリストを返す（指定がない場合はnil）
+func (f *Fetcher) filterList() (*filterList, error) {
+	if len(f.config.filterLists) == 0 {
+		return nil, nil
+	}
+	f.filtersOnce.Do(func() {
+		fl, err := loadFilterLists(f.config.filterLists)
+		if err != nil {
+			f.filtersErr = &FetchError{
+				Code:    ErrFilterListLoadFailed,
+				Message: "フィルタリストの読み込みに失敗しました",
+				Cause:   err,
+			}
+			return
+		}
+		f.filters = fl
+	})
+	return f.filters, f.filtersErr
+}
+
 // getBrowserAndPage はブラウザとページを取得し、クリーンアップ関数を返す
-func (f *Fetcher) getBrowserAndPage() (*rod.Browser, *rod.Page, func(), error) {
+func (f *Fetcher) getBrowserAndPage(ctx context.Context, cfg *fetchConfig) (*rod.Browser, *rod.Page, func(), error) {
 	f.mu.Lock()
 	started := f.started
 	browser := f.browser
+	pages := f.pages
 	f.mu.Unlock()
 
+	if cfg.session != nil && !started {
+		return nil, nil, nil, &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションのFetcherが起動していません",
+		}
+	}
+
+	if started && (cfg.isolated || cfg.session != nil) {
+		// 分離/セッションモード: 専用のブラウザコンテキストでタブを作成
+		// プールモードでも同時タブ数の上限は守るが、タブは再利用しない
+		if pages != nil {
+			if err := pages.wait(ctx); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+
+		var contextBrowser *rod.Browser
+		var page *rod.Page
+		var err error
+		if cfg.session != nil {
+			contextBrowser = cfg.session.browser
+			page, err = f.createPage(contextBrowser)
+		} else {
+			contextBrowser, page, err = f.createIsolatedPage(browser)
+		}
+		if err != nil {
+			if pages != nil {
+				pages.done()
+			}
+			return nil, nil, nil, err
+		}
+
+		cleanup := func() {
+			_ = page.Close()
+			// 分離モードのコンテキストはFetchごとに破棄し、セッションのコンテキストは残す
+			if cfg.session == nil {
+				_ = contextBrowser.Close()
+			}
+			if pages != nil {
+				pages.done()
+			}
+		}
+		return contextBrowser, page, cleanup, nil
+	}
+
+	if started && pages != nil {
+		// プールモード: 空きタブを待って再利用
+		page, err := pages.acquire(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		cleanup := func() {
+			pages.release(page)
+		}
+		return browser, page, cleanup, nil
+	}
+
 	if started {
 		// 高速モード: 既存ブラウザでタブを作成
 		page, err := f.createPage(browser)
@@ -220,7 +548,7 @@ func (f *Fetcher) getBrowserAndPage() (*rod.Browser, *rod.Page, func(), error) {
 			return nil, nil, nil, err
 		}
 		cleanup := func() {
-			page.MustClose()
+			_ = page.Close()
 		}
 		return browser, page, cleanup, nil
 	}
@@ -238,14 +566,20 @@ func (f *Fetcher) getBrowserAndPage() (*rod.Browser, *rod.Page, func(), error) {
 	}
 
 	cleanup := func() {
-		page.MustClose()
-		newBrowser.MustClose()
+		_ = page.Close()
+		_ = newBrowser.Close()
 	}
 	return newBrowser, page, cleanup, nil
 }
 
 // launchBrowser はブラウザを起動
 func (f *Fetcher) launchBrowser() (*rod.Browser, error) {
+	browser, _, err := f.launchBrowserProcess()
+	return browser, err
+}
+
+// launchBrowserProcess はブラウザを起動し、プロセスを管理するlauncherも返す
+func (f *Fetcher) launchBrowserProcess() (*rod.Browser, *launcher.Launcher, error) {
 	browserPath := f.config.browserPath
 	if browserPath == "" {
 		browserPath = detectBrowserPath()
@@ -273,15 +607,50 @@ func (f *Fetcher) launchBrowser() (*rod.Browser, error) {
 
 	launchURL, err := l.Launch()
 	if err != nil {
-		return nil, &FetchError{
+		return nil, nil, &FetchError{
 			Code:    ErrBrowserLaunchFailed,
 			Message: "ブラウザの起動に失敗しました",
 			Cause:   err,
 		}
 	}
 
-	browser := rod.New().ControlURL(launchURL).MustConnect()
-	return browser, nil
+	browser := rod.New().ControlURL(launchURL)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		return nil, nil, &FetchError{
+			Code:    ErrBrowserLaunchFailed,
+			Message: "ブラウザへの接続に失敗しました",
+			Cause:   err,
+		}
+	}
+	return browser, l, nil
+}
+
+// ping はCDP経由でブラウザが応答するかを確認する
+func (f *Fetcher) ping(timeout time.Duration) bool {
+	f.mu.Lock()
+	browser := f.browser
+	f.mu.Unlock()
+
+	if browser == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := proto.BrowserGetVersion{}.Call(browser.Context(ctx))
+	return err == nil
+}
+
+// pid は高速モードで起動したブラウザのプロセスIDを返す（未起動時は0）
+func (f *Fetcher) pid() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.launcher == nil {
+		return 0
+	}
+	return f.launcher.PID()
 }
 
 // createPage はページを作成
@@ -301,6 +670,21 @@ func (f *Fetcher) createPage(browser *rod.Browser) (*rod.Page, error) {
 	return page, nil
 }
 
+// createIsolatedPage は専用のブラウザコンテキスト（Target.createBrowserContext）でページを作成
+// 返されるBrowserのClose()でコンテキストごと破棄される
+func (f *Fetcher) createIsolatedPage(browser *rod.Browser) (*rod.Browser, *rod.Page, error) {
+	contextBrowser, err := browser.Incognito()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ブラウザコンテキストの作成に失敗: %w", err)
+	}
+	page, err := f.createPage(contextBrowser)
+	if err != nil {
+		_ = contextBrowser.Close()
+		return nil, nil, err
+	}
+	return contextBrowser, page, nil
+}
+
 // detectBrowserPath はブラウザのパスを自動検出
 func detectBrowserPath() string {
 	headlessPath := "/headless-shell/headless-shell"
@@ -311,6 +695,15 @@ func detectBrowserPath() string {
 	return path
 }
 
+// setExtraHeaders はCDP Network.setExtraHTTPHeadersで全リクエストにヘッダーを追加する
+func setExtraHeaders(page *rod.Page, headers map[string]string) error {
+	h := make(proto.NetworkHeaders, len(headers))
+	for k, v := range headers {
+		h[k] = gson.New(v)
+	}
+	return proto.NetworkSetExtraHTTPHeaders{Headers: h}.Call(page)
+}
+
 // waitForPage は待機戦略に応じてページを待機
 func waitForPage(page *rod.Page, strategy WaitStrategy) error {
 	switch strategy {
@@ -335,5 +728,11 @@ func waitForPage(page *rod.Page, strategy WaitStrategy) error {
 
 // waitForSelector はセレクタが表示されるまで待機
 func waitForSelector(page *rod.Page, selector string, timeout time.Duration) error {
-	return page.Timeout(timeout).MustElement(selector).WaitVisible()
+	p := page.Timeout(timeout)
+	defer p.CancelTimeout()
+	el, err := p.Element(selector)
+	if err != nil {
+		return err
+	}
+	return el.WaitVisible()
 }
@@ -0,0 +1,581 @@
+package htmlfetch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Adblock Plus形式のリソースタイプ（ビットマスク）
+const (
+	filterTypeScript uint16 = 1 << iota
+	filterTypeImage
+	filterTypeStylesheet
+	filterTypeFont
+	filterTypeMedia
+	filterTypeXHR
+	filterTypeSubdocument
+	filterTypePing
+	filterTypeWebSocket
+	filterTypeObject
+	filterTypeOther
+
+	filterTypeAll = 1<<iota - 1
+)
+
+// filterTypeOptions はフィルタのオプション名とリソースタイプの対応
+var filterTypeOptions = map[string]uint16{
+	"script":            filterTypeScript,
+	"image":             filterTypeImage,
+	"stylesheet":        filterTypeStylesheet,
+	"css":               filterTypeStylesheet,
+	"font":              filterTypeFont,
+	"media":             filterTypeMedia,
+	"xmlhttprequest":    filterTypeXHR,
+	"xhr":               filterTypeXHR,
+	"subdocument":       filterTypeSubdocument,
+	"frame":             filterTypeSubdocument,
+	"ping":              filterTypePing,
+	"beacon":            filterTypePing,
+	"websocket":         filterTypeWebSocket,
+	"object":            filterTypeObject,
+	"object-subrequest": filterTypeObject,
+	"other":             filterTypeOther,
+}
+
+// cdpFilterTypes はCDPのリソースタイプとAdblock Plus形式のリソースタイプの対応
+// 対応がないものはfilterTypeOtherとして扱う
+var cdpFilterTypes = map[string]uint16{
+	"Script":             filterTypeScript,
+	"Image":              filterTypeImage,
+	"Stylesheet":         filterTypeStylesheet,
+	"Font":               filterTypeFont,
+	"Media":              filterTypeMedia,
+	"TextTrack":          filterTypeMedia,
+	"XHR":                filterTypeXHR,
+	"Fetch":              filterTypeXHR,
+	"EventSource":        filterTypeXHR,
+	"Document":           filterTypeSubdocument,
+	"Ping":               filterTypePing,
+	"CSPViolationReport": filterTypePing,
+	"WebSocket":          filterTypeWebSocket,
+}
+
+// filterRule はネットワークフィルタの1行をコンパイルしたもの
+type filterRule struct {
+	pattern      string         // 小文字化したパターン（アンカー・オプションを除く）
+	re           *regexp.Regexp // /.../形式の正規表現フィルタ
+	anchorDomain bool           // "||" ホスト名の先頭（またはサブドメインの境界）に一致
+	anchorStart  bool           // "|" URLの先頭に一致
+	anchorEnd    bool           // 末尾の"|" URLの末尾に一致
+	types        uint16         // 適用するリソースタイプ
+	thirdParty   int            // 1: サードパーティのみ、-1: ファーストパーティのみ、0: 両方
+	domains      []string       // $domain= 適用するページのドメイン
+	notDomains   []string       // $domain=~ 除外するページのドメイン
+	important    bool           // $important 例外フィルタより優先する
+	document     bool           // @@...$document ページ全体でブロックを無効化する
+	elemhide     bool           // @@...$elemhide ページ全体で要素隠しフィルタを無効化する
+	generichide  bool           // @@...$generichide ページ全体でドメイン指定のない要素隠しフィルタを無効化する
+}
+
+// filterSet はフィルタをホスト名・トークンで索引したもの
+// 全フィルタを線形に照合せず、URLに含まれるホスト名・トークンに対応するフィルタのみ照合する
+type filterSet struct {
+	byHost  map[string][]*filterRule
+	byToken map[string][]*filterRule
+	generic []*filterRule
+}
+
+// filterList はAdblock Plus / uBlock Origin形式のフィルタリスト
+type filterList struct {
+	block     filterSet
+	exception filterSet
+	documents []*filterRule // @@...$document
+
+	// 要素隠しフィルタ（##）
+	hideGeneric  []*cosmeticFilter            // ドメイン指定のないフィルタ
+	hideByDomain map[string][]*cosmeticFilter // ドメイン指定のあるフィルタ（指定したドメインごと）
+	unhide       map[string][]*cosmeticFilter // 例外フィルタ（#@#）のセレクタごと
+	elemhide     []*filterRule                // @@...$elemhide
+	generichide  []*filterRule                // @@...$generichide
+}
+
+// filterRequest は照合するリクエスト
+type filterRequest struct {
+	url        string // 小文字化したURL
+	hostStart  int
+	hostEnd    int
+	host       string
+	docHost    string
+	types      uint16
+	thirdParty bool
+	tokens     []string
+}
+
+// loadFilterLists はファイルからフィルタリストを読み込む
+func loadFilterLists(paths []string) (*filterList, error) {
+	fl := newFilterList()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = fl.parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return fl, nil
+}
+
+// newFilterList は空のフィルタリストを作成
+func newFilterList() *filterList {
+	return &filterList{
+		block:        newFilterSet(),
+		exception:    newFilterSet(),
+		hideByDomain: make(map[string][]*cosmeticFilter),
+		unhide:       make(map[string][]*cosmeticFilter),
+	}
+}
+
+func newFilterSet() filterSet {
+	return filterSet{
+		byHost:  make(map[string][]*filterRule),
+		byToken: make(map[string][]*filterRule),
+	}
+}
+
+// parse はフィルタリストを1行ずつ読み込む
+// コメント・スクリプトレット等・対応していないオプションを含むフィルタは読み飛ばす
+func (fl *filterList) parse(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		fl.addLine(sc.Text())
+	}
+	return sc.Err()
+}
+
+// addLine はフィルタリストの1行を追加する
+func (fl *filterList) addLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '!' || line[0] == '[' {
+		return
+	}
+	// 要素隠し・スクリプトレット等のフィルタ（##, #@#, #?#, #$#, #%# 等）
+	if isCosmeticFilter(line) {
+		fl.addCosmetic(line)
+		return
+	}
+
+	exception := strings.HasPrefix(line, "@@")
+	if exception {
+		line = line[2:]
+	}
+	rule, ok := parseFilterRule(line, exception)
+	if !ok {
+		return
+	}
+	switch {
+	case rule.document:
+		fl.documents = append(fl.documents, rule)
+	case rule.elemhide:
+		fl.elemhide = append(fl.elemhide, rule)
+	case rule.generichide:
+		fl.generichide = append(fl.generichide, rule)
+	case exception:
+		fl.exception.add(rule)
+	default:
+		fl.block.add(rule)
+	}
+}
+
+// isCosmeticFilter はネットワークフィルタではない行かを判定
+func isCosmeticFilter(line string) bool {
+	i := strings.IndexByte(line, '#')
+	if i < 0 || i+1 >= len(line) {
+		return false
+	}
+	switch line[i+1] {
+	case '#', '@', '?', '$', '%':
+		return true
+	}
+	return false
+}
+
+// parseFilterRule はネットワークフィルタを解析する
+func parseFilterRule(line string, exception bool) (*filterRule, bool) {
+	rule := &filterRule{types: filterTypeAll}
+
+	// 正規表現フィルタ（/.../ または /.../$options）
+	pattern, options := line, ""
+	if end := strings.LastIndex(line, "/$"); strings.HasPrefix(line, "/") && end > 0 {
+		pattern, options = line[:end+1], line[end+2:]
+	} else if i := strings.LastIndexByte(line, '$'); i >= 0 && !strings.HasSuffix(line, "/") {
+		pattern, options = line[:i], line[i+1:]
+	}
+
+	if options != "" && !rule.parseOptions(options, exception) {
+		return nil, false
+	}
+
+	if len(pattern) > 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+		re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, false
+		}
+		rule.re = re
+		return rule, true
+	}
+
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "||") {
+		rule.anchorDomain = true
+		pattern = pattern[2:]
+	} else if strings.HasPrefix(pattern, "|") {
+		rule.anchorStart = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "|") {
+		rule.anchorEnd = true
+		pattern = pattern[:len(pattern)-1]
+	}
+	// 先頭・末尾の"*"は省略と同じ
+	if !rule.anchorDomain && !rule.anchorStart {
+		pattern = strings.TrimLeft(pattern, "*")
+	}
+	if !rule.anchorEnd {
+		pattern = strings.TrimRight(pattern, "*")
+	}
+	if pattern == "" && !rule.document && !rule.elemhide && !rule.generichide && rule.domains == nil {
+		// 全URLに一致するフィルタは誤ブロックが多いため読み飛ばす
+		return nil, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// parseOptions は$以降のオプションを解析する
+// 対応していないオプションを含む場合はfalseを返す
+func (r *filterRule) parseOptions(options string, exception bool) bool {
+	var include, exclude uint16
+	for _, opt := range strings.Split(options, ",") {
+		opt = strings.ToLower(strings.TrimSpace(opt))
+		negated := strings.HasPrefix(opt, "~")
+		name := strings.TrimPrefix(opt, "~")
+
+		if t, ok := filterTypeOptions[name]; ok {
+			if negated {
+				exclude |= t
+			} else {
+				include |= t
+			}
+			continue
+		}
+
+		switch {
+		case name == "third-party" || name == "3p":
+			r.thirdParty = 1
+			if negated {
+				r.thirdParty = -1
+			}
+		case name == "first-party" || name == "1p":
+			r.thirdParty = -1
+			if negated {
+				r.thirdParty = 1
+			}
+		case strings.HasPrefix(opt, "domain="):
+			for _, d := range strings.Split(strings.TrimPrefix(opt, "domain="), "|") {
+				if strings.HasPrefix(d, "~") {
+					r.notDomains = append(r.notDomains, d[1:])
+				} else if d != "" {
+					r.domains = append(r.domains, d)
+				}
+			}
+		case opt == "important":
+			r.important = true
+		case opt == "match-case":
+			// URLは大文字小文字を区別せずに照合する
+		case opt == "document" && exception:
+			r.document = true
+		case (opt == "elemhide" || opt == "ehide") && exception:
+			r.elemhide = true
+		case (opt == "generichide" || opt == "ghide") && exception:
+			r.generichide = true
+		default:
+			// $popup, $csp, $redirect, $removeparam等の対応していないオプション
+			return false
+		}
+	}
+
+	if include != 0 {
+		r.types = include
+	}
+	r.types &^= exclude
+	return r.types != 0
+}
+
+// add はフィルタを索引に追加する
+func (s *filterSet) add(r *filterRule) {
+	if r.re == nil {
+		if host, ok := filterHost(r); ok {
+			s.byHost[host] = append(s.byHost[host], r)
+			return
+		}
+		if token := filterToken(r); token != "" {
+			s.byToken[token] = append(s.byToken[token], r)
+			return
+		}
+	}
+	s.generic = append(s.generic, r)
+}
+
+// filterHost は"||example.com^"のようにホスト名全体を指定したフィルタのホスト名を返す
+func filterHost(r *filterRule) (string, bool) {
+	if !r.anchorDomain {
+		return "", false
+	}
+	end := strings.IndexAny(r.pattern, "^/")
+	if end <= 0 {
+		return "", false
+	}
+	host := r.pattern[:end]
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		if !isTokenChar(c) && c != '.' && c != '-' {
+			return "", false
+		}
+	}
+	return host, true
+}
+
+// filterToken はフィルタが一致するURLに必ず含まれるトークンのうち最長のものを返す
+// ワイルドカードに隣接するトークンはURL中でより長いトークンの一部になりうるため使わない
+func filterToken(r *filterRule) string {
+	p := r.pattern
+	best := ""
+	for i := 0; i < len(p); {
+		if !isTokenChar(p[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(p) && isTokenChar(p[i]) {
+			i++
+		}
+		leftOK := (start == 0 && (r.anchorStart || r.anchorDomain)) || (start > 0 && p[start-1] != '*')
+		rightOK := (i == len(p) && r.anchorEnd) || (i < len(p) && p[i] != '*')
+		if leftOK && rightOK && i-start > len(best) {
+			best = p[start:i]
+		}
+	}
+	if len(best) < 2 {
+		return ""
+	}
+	return best
+}
+
+func isTokenChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '%'
+}
+
+// isSeparator は"^"に一致する区切り文字かを判定
+func isSeparator(c byte) bool {
+	return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '%')
+}
+
+// newFilterRequest は照合するリクエストの情報を作成
+func newFilterRequest(resourceType, reqURL, documentURL string) *filterRequest {
+	req := &filterRequest{url: strings.ToLower(reqURL)}
+
+	// ホスト名の位置（"||"アンカーの照合に使う）
+	if i := strings.Index(req.url, "://"); i >= 0 {
+		req.hostStart = i + 3
+		req.hostEnd = len(req.url)
+		if j := strings.IndexAny(req.url[req.hostStart:], "/?#"); j >= 0 {
+			req.hostEnd = req.hostStart + j
+		}
+		hostPort := req.url[req.hostStart:req.hostEnd]
+		if at := strings.LastIndexByte(hostPort, '@'); at >= 0 {
+			req.hostStart += at + 1
+		}
+	}
+	req.host = hostOf(reqURL)
+	req.docHost = hostOf(documentURL)
+
+	req.types = filterTypeOther
+	if t, ok := cdpFilterTypes[resourceType]; ok {
+		req.types = t
+	}
+	if req.docHost != "" && req.host != "" {
+		req.thirdParty = registrableDomain(req.host) != registrableDomain(req.docHost)
+	}
+
+	for i := 0; i < len(req.url); {
+		if !isTokenChar(req.url[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(req.url) && isTokenChar(req.url[i]) {
+			i++
+		}
+		if i-start >= 2 {
+			req.tokens = append(req.tokens, req.url[start:i])
+		}
+	}
+	return req
+}
+
+// registrableDomain はeTLD+1（例: "www.example.co.jp" → "example.co.jp"）を返す
+func registrableDomain(host string) string {
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
+
+// match はリクエストをブロックするかを判定
+// documentURLはリクエスト元のページのURLで、サードパーティ判定・$domainの照合に使う
+func (fl *filterList) match(resourceType, reqURL, documentURL string) bool {
+	// ページ自体（メインフレームのドキュメント）はブロックしない
+	if resourceType == "Document" && reqURL == documentURL {
+		return false
+	}
+	if !isFetchableURL(reqURL) {
+		return false
+	}
+
+	req := newFilterRequest(resourceType, reqURL, documentURL)
+	rule := fl.block.find(req)
+	if rule == nil {
+		return false
+	}
+	if rule.important {
+		return true
+	}
+	if fl.exception.find(req) != nil {
+		return false
+	}
+	if documentURL != "" && len(fl.documents) > 0 {
+		doc := newFilterRequest("", documentURL, documentURL)
+		for _, r := range fl.documents {
+			if r.matchURL(doc) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// find はリクエストに一致するフィルタを返す（$importantのフィルタを優先する）
+func (s *filterSet) find(req *filterRequest) *filterRule {
+	var found *filterRule
+	check := func(rules []*filterRule) bool {
+		for _, r := range rules {
+			if r.matches(req) {
+				found = r
+				if r.important {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// ホスト名とその親ドメイン（a.b.example.com → b.example.com → example.com → com）
+	for host := req.host; host != ""; {
+		if check(s.byHost[host]) {
+			return found
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	for _, token := range req.tokens {
+		if check(s.byToken[token]) {
+			return found
+		}
+	}
+	check(s.generic)
+	return found
+}
+
+// matches はリクエストがフィルタのオプションとパターンに一致するかを判定
+func (r *filterRule) matches(req *filterRequest) bool {
+	if r.types&req.types == 0 {
+		return false
+	}
+	if r.thirdParty == 1 && !req.thirdParty || r.thirdParty == -1 && req.thirdParty {
+		return false
+	}
+	if len(r.domains) > 0 && !matchDomain(req.docHost, r.domains) {
+		return false
+	}
+	if len(r.notDomains) > 0 && matchDomain(req.docHost, r.notDomains) {
+		return false
+	}
+	return r.matchURL(req)
+}
+
+// matchURL はURLがフィルタのパターンに一致するかを判定
+func (r *filterRule) matchURL(req *filterRequest) bool {
+	if r.re != nil {
+		return r.re.MatchString(req.url)
+	}
+
+	pattern := r.pattern
+	if !r.anchorEnd {
+		pattern += "*"
+	}
+	switch {
+	case r.anchorDomain:
+		// ホスト名の先頭、またはホスト名中の"."の直後から一致
+		for i := req.hostStart; i < req.hostEnd; i++ {
+			if (i == req.hostStart || req.url[i-1] == '.') && matchWildcard(pattern, req.url[i:]) {
+				return true
+			}
+		}
+		return false
+	case r.anchorStart:
+		return matchWildcard(pattern, req.url)
+	default:
+		return matchWildcard("*"+pattern, req.url)
+	}
+}
+
+// matchWildcard はパターン全体が文字列全体に一致するかを判定
+// "*" は任意の文字列、"^" は区切り文字または文字列の末尾に一致する
+func matchWildcard(pattern, s string) bool {
+	pi, si := 0, 0
+	star, mark := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(pattern) && pattern[pi] == '*':
+			star, mark = pi, si
+			pi++
+		case pi < len(pattern) && (pattern[pi] == s[si] || pattern[pi] == '^' && isSeparator(s[si])):
+			pi++
+			si++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(pattern) && (pattern[pi] == '*' || pattern[pi] == '^') {
+		pi++
+	}
+	return pi == len(pattern)
+}
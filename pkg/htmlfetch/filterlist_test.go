@@ -0,0 +1,124 @@
+package htmlfetch
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFilterList = `[Adblock Plus 2.0]
+! Title: テスト用フィルタリスト
+||ads.example.net^
+||tracker.example.org^$third-party
+||cdn.example.com/ads/*$script,image
+/banner/*$image,domain=news.example.jp|~sports.news.example.jp
+-ad-300x250.
+|https://start.example.com/
+/analytics.js|
+/pixel\.gif\?id=\d+/$image
+||media.example.net^$~image
+@@||ads.example.net/allowed/*
+||important.example.net^$important
+@@||important.example.net^
+@@||trusted.example.jp^$document
+##.ad-banner
+example.com#@#.sponsored
+||popup.example.net^$popup
+`
+
+func TestFilterList_Match(t *testing.T) {
+	fl := newFilterList()
+	if err := fl.parse(strings.NewReader(testFilterList)); err != nil {
+		t.Fatal(err)
+	}
+
+	const page = "https://www.example.co.jp/index.html"
+	tests := []struct {
+		resourceType string
+		url          string
+		documentURL  string
+		want         bool
+	}{
+		// ホスト名アンカー（サブドメインにも一致し、前方一致のホスト名には一致しない）
+		{"Script", "https://ads.example.net/tag.js", page, true},
+		{"Script", "https://x.ads.example.net/tag.js", page, true},
+		{"Script", "https://badads.example.net/tag.js", page, false},
+		{"Script", "https://ads.example.network/tag.js", page, false},
+		// 例外フィルタ
+		{"Script", "https://ads.example.net/allowed/tag.js", page, false},
+		// $third-party（eTLD+1で判定する）
+		{"Script", "https://tracker.example.org/t.js", page, true},
+		{"Script", "https://tracker.example.org/t.js", "https://www.example.org/", false},
+		// リソースタイプ
+		{"Image", "https://cdn.example.com/ads/a.png", page, true},
+		{"Stylesheet", "https://cdn.example.com/ads/a.css", page, false},
+		{"Script", "https://media.example.net/a.js", page, true},
+		{"Image", "https://media.example.net/a.png", page, false},
+		// $domain
+		{"Image", "https://img.example.com/banner/1.png", "https://news.example.jp/", true},
+		{"Image", "https://img.example.com/banner/1.png", "https://sports.news.example.jp/", false},
+		{"Image", "https://img.example.com/banner/1.png", page, false},
+		// アンカーなしのパターン・先頭/末尾アンカー
+		{"Image", "https://example.com/img/top-ad-300x250.png", page, true},
+		{"Document", "https://start.example.com/frame.html", page, true},
+		{"Document", "https://other.example.com/?u=https://start.example.com/", page, false},
+		{"Script", "https://example.com/js/analytics.js", page, true},
+		{"Script", "https://example.com/js/analytics.js?v=2", page, false},
+		// 正規表現
+		{"Image", "https://example.com/pixel.gif?id=123", page, true},
+		{"Image", "https://example.com/pixel.gif?id=abc", page, false},
+		// $importantは例外フィルタより優先する
+		{"Script", "https://important.example.net/a.js", page, true},
+		// @@...$documentのページではブロックしない
+		{"Script", "https://ads.example.net/tag.js", "https://www.trusted.example.jp/", false},
+		// ページ自体はブロックしない
+		{"Document", "https://ads.example.net/", "https://ads.example.net/", false},
+		// 対応していないオプション（$popup）のフィルタは読み飛ばす
+		{"Document", "https://popup.example.net/", page, false},
+	}
+	for _, tt := range tests {
+		if got := fl.match(tt.resourceType, tt.url, tt.documentURL); got != tt.want {
+			t.Errorf("match(%q, %q, %q) = %v, want %v", tt.resourceType, tt.url, tt.documentURL, got, tt.want)
+		}
+	}
+}
+
+func TestFilterList_Index(t *testing.T) {
+	fl := newFilterList()
+	if err := fl.parse(strings.NewReader(testFilterList)); err != nil {
+		t.Fatal(err)
+	}
+
+	// ホスト名を指定したフィルタはホスト名で索引される
+	if len(fl.block.byHost["ads.example.net"]) != 1 {
+		t.Errorf("byHost: %v", fl.block.byHost)
+	}
+	// ワイルドカードに隣接しないトークンで索引される
+	if len(fl.block.byToken["analytics"]) != 1 {
+		t.Errorf("byToken: %v", fl.block.byToken)
+	}
+	// 正規表現フィルタは索引できない
+	if len(fl.block.generic) != 1 {
+		t.Errorf("generic: %d件", len(fl.block.generic))
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"example.com^", "example.com/", true},
+		{"example.com^", "example.com", true},
+		{"example.com^", "example.com.evil", false},
+		{"*/ads/*", "https://example.com/ads/a.js", true},
+		{"*/ads/*", "https://example.com/adsx/a.js", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"^foo^", "/foo?", true},
+	}
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
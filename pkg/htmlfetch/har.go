@@ -0,0 +1,421 @@
+package htmlfetch
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// HAR はHAR 1.2形式のネットワークログ
+// encoding/jsonでそのまま .har ファイルとして書き出せる
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog はHARのルート要素
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator はHARを作成したアプリケーション
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage は取得したページ
+type HARPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings はページの読み込み時間（ミリ秒、不明な場合は-1）
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry は1回のリクエストとレスポンス
+// ブロック・失敗したリクエストはステータス0で記録し、理由を_error・_blockedReasonに入れる
+// ブロッキング設定でブロックした場合は、ルール（NetworkStats.BlockedByRuleのキー）を_blockedByに入れる
+type HAREntry struct {
+	Pageref         string      `json:"pageref"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+	BlockedReason   string      `json:"_blockedReason,omitempty"`
+	BlockedBy       string      `json:"_blockedBy,omitempty"`
+}
+
+// HARRequest はリクエスト
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse はレスポンス
+type HARResponse struct {
+	Status       int            `json:"status"`
+	StatusText   string         `json:"statusText"`
+	HTTPVersion  string         `json:"httpVersion"`
+	Cookies      []HARCookie    `json:"cookies"`
+	Headers      []HARNameValue `json:"headers"`
+	Content      HARContent     `json:"content"`
+	RedirectURL  string         `json:"redirectURL"`
+	HeadersSize  int64          `json:"headersSize"`
+	BodySize     int64          `json:"bodySize"`
+	TransferSize int64          `json:"_transferSize"`
+}
+
+// HARContent はレスポンスボディの情報
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARCookie はリクエスト・レスポンスのCookie
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+// HARNameValue はヘッダー・クエリ文字列の名前と値
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData はリクエストボディ
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARTimings はリクエストの各フェーズの時間（ミリ秒、該当しない場合は-1）
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harPageID はHARPageとHAREntryを関連付けるID（1回のFetchで1ページ）
+const harPageID = "page_1"
+
+// harPageInfo はHARPageの作成に使うページの情報
+type harPageInfo struct {
+	started       time.Time
+	title         string
+	onContentLoad float64
+	onLoad        float64
+}
+
+// loadHARPageInfo はページのタイトルとNavigation Timingを取得する
+func loadHARPageInfo(page *rod.Page, started time.Time) harPageInfo {
+	info := harPageInfo{started: started, onContentLoad: -1, onLoad: -1}
+
+	res, err := page.Eval(`() => {
+		const nav = performance.getEntriesByType('navigation')[0];
+		return {
+			title: document.title,
+			onContentLoad: nav ? nav.domContentLoadedEventEnd : -1,
+			onLoad: nav && nav.loadEventEnd > 0 ? nav.loadEventEnd : -1,
+		};
+	}`)
+	if err != nil {
+		return info
+	}
+
+	var v struct {
+		Title         string  `json:"title"`
+		OnContentLoad float64 `json:"onContentLoad"`
+		OnLoad        float64 `json:"onLoad"`
+	}
+	if err := res.Value.Unmarshal(&v); err == nil {
+		info.title = v.Title
+		info.onContentLoad = v.OnContentLoad
+		info.onLoad = v.OnLoad
+	}
+	return info
+}
+
+// buildHAR は記録したリクエストからHARを作成する
+func buildHAR(exchanges []*exchange, page harPageInfo) *HAR {
+	started := page.started
+	if len(exchanges) > 0 && !exchanges[0].wallTime.IsZero() {
+		started = exchanges[0].wallTime
+	}
+
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "nz-html-fetch", Version: "1.0"},
+		Pages: []HARPage{{
+			StartedDateTime: formatHARTime(started),
+			ID:              harPageID,
+			Title:           page.title,
+			PageTimings: HARPageTimings{
+				OnContentLoad: page.onContentLoad,
+				OnLoad:        page.onLoad,
+			},
+		}},
+		Entries: make([]HAREntry, 0, len(exchanges)),
+	}}
+
+	for _, ex := range exchanges {
+		if strings.HasPrefix(ex.url, "data:") {
+			continue
+		}
+		har.Log.Entries = append(har.Log.Entries, buildHAREntry(ex))
+	}
+	return har
+}
+
+// buildHAREntry は1回のリクエストをHAREntryに変換する
+func buildHAREntry(ex *exchange) HAREntry {
+	requestHeaders := harHeaders(ex.requestHeaders)
+	entry := HAREntry{
+		Pageref:         harPageID,
+		StartedDateTime: formatHARTime(ex.wallTime),
+		Request: HARRequest{
+			Method:      ex.method,
+			URL:         ex.url,
+			HTTPVersion: "http/1.1",
+			Cookies:     harRequestCookies(ex.requestHeaders),
+			Headers:     requestHeaders,
+			QueryString: harQueryString(ex.url),
+			HeadersSize: harHeadersSize(ex.requestHeadersSize),
+			BodySize:    int64(len(ex.postData)),
+		},
+		Response: HARResponse{
+			Cookies:      []HARCookie{},
+			Headers:      []HARNameValue{},
+			Content:      HARContent{MimeType: "x-unknown"},
+			HeadersSize:  harHeadersSize(ex.responseHeadersSize),
+			BodySize:     -1,
+			TransferSize: int64(ex.encodedLength),
+		},
+		ResourceType:  ex.resourceType,
+		Error:         ex.errorText,
+		BlockedReason: ex.blockedReason,
+		BlockedBy:     ex.blockedBy,
+	}
+	if ex.postData != "" {
+		entry.Request.PostData = &HARPostData{
+			MimeType: headerValue(requestHeaders, "Content-Type"),
+			Text:     ex.postData,
+		}
+	}
+
+	if res := ex.response; res != nil {
+		responseHeaders := harHeaders(res.Headers)
+		entry.Request.HTTPVersion = harHTTPVersion(res.Protocol)
+		entry.Response.Status = res.Status
+		entry.Response.StatusText = res.StatusText
+		if entry.Response.StatusText == "" {
+			entry.Response.StatusText = http.StatusText(res.Status)
+		}
+		entry.Response.HTTPVersion = harHTTPVersion(res.Protocol)
+		entry.Response.Cookies = harResponseCookies(res.Headers)
+		entry.Response.Headers = responseHeaders
+		entry.Response.Content = HARContent{Size: ex.dataLength, MimeType: res.MIMEType}
+		entry.ServerIPAddress = res.RemoteIPAddress
+
+		if ex.redirected {
+			entry.Response.BodySize = 0
+			if loc := headerValue(responseHeaders, "Location"); loc != "" {
+				entry.Response.RedirectURL = resolveURL(ex.url, loc)
+			}
+		} else if res.FromDiskCache || res.FromServiceWorker {
+			entry.Response.BodySize = 0
+		}
+	}
+
+	entry.Timings = harTimings(ex)
+	for _, v := range []float64{entry.Timings.Blocked, entry.Timings.DNS, entry.Timings.Connect,
+		entry.Timings.Send, entry.Timings.Wait, entry.Timings.Receive} {
+		if v > 0 {
+			entry.Time += v
+		}
+	}
+	return entry
+}
+
+// harHeadersSize はヘッダーのサイズを返す（ExtraInfoイベントが届かず不明な場合は-1）
+func harHeadersSize(size int64) int64 {
+	if size <= 0 {
+		return -1
+	}
+	return size
+}
+
+// harTimings はResourceTimingからHARのタイミングを計算する
+// 計算方法はChrome DevToolsのHARエクスポートに合わせている
+func harTimings(ex *exchange) HARTimings {
+	t := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+
+	// キャッシュからの応答や失敗したリクエストにはResourceTimingがないため、全体を受信時間とする
+	if ex.response == nil || ex.response.Timing == nil {
+		if ex.endTime > 0 && ex.startTime > 0 {
+			t.Receive = float64(ex.endTime-ex.startTime) * 1000
+		}
+		return t
+	}
+
+	rt := ex.response.Timing
+
+	// 最初のフェーズが始まるまでの時間を待機時間とする
+	for _, start := range []float64{rt.DNSStart, rt.ConnectStart, rt.SendStart} {
+		if start >= 0 {
+			t.Blocked = start
+			break
+		}
+	}
+	if rt.DNSStart >= 0 {
+		t.DNS = rt.DNSEnd - rt.DNSStart
+	}
+	if rt.ConnectStart >= 0 {
+		t.Connect = rt.ConnectEnd - rt.ConnectStart
+	}
+	if rt.SslStart >= 0 {
+		t.SSL = rt.SslEnd - rt.SslStart
+	}
+	t.Send = rt.SendEnd - rt.SendStart
+	t.Wait = rt.ReceiveHeadersEnd - rt.SendEnd
+
+	if ex.endTime > 0 {
+		t.Receive = (float64(ex.endTime)-rt.RequestTime)*1000 - rt.ReceiveHeadersEnd
+	}
+	if t.Receive < 0 {
+		t.Receive = 0
+	}
+	return t
+}
+
+// harHeaders はヘッダーを名前順のリストに変換する
+func harHeaders(headers proto.NetworkHeaders) []HARNameValue {
+	result := []HARNameValue{}
+	for _, l := range sortedHeaderLines(headers) {
+		result = append(result, HARNameValue{Name: l[0], Value: l[1]})
+	}
+	return result
+}
+
+// headerValue はヘッダーの値を大文字小文字を区別せずに返す
+func headerValue(headers []HARNameValue, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// harQueryString はURLのクエリ文字列をリストに変換する
+func harQueryString(rawURL string) []HARNameValue {
+	result := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range query[k] {
+			result = append(result, HARNameValue{Name: k, Value: v})
+		}
+	}
+	return result
+}
+
+// harRequestCookies はCookieヘッダーからCookieを取り出す
+func harRequestCookies(headers proto.NetworkHeaders) []HARCookie {
+	h := http.Header{}
+	for _, l := range headerLines(headers) {
+		h.Add(l[0], l[1])
+	}
+	result := []HARCookie{}
+	for _, c := range (&http.Request{Header: h}).Cookies() {
+		result = append(result, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	return result
+}
+
+// harResponseCookies はSet-CookieヘッダーからCookieを取り出す
+func harResponseCookies(headers proto.NetworkHeaders) []HARCookie {
+	h := http.Header{}
+	for _, l := range headerLines(headers) {
+		h.Add(l[0], l[1])
+	}
+	result := []HARCookie{}
+	for _, c := range (&http.Response{Header: h}).Cookies() {
+		cookie := HARCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = formatHARTime(c.Expires)
+		}
+		result = append(result, cookie)
+	}
+	return result
+}
+
+// harHTTPVersion はCDPのプロトコル名をHARのhttpVersionに変換する
+func harHTTPVersion(protocol string) string {
+	switch protocol {
+	case "":
+		return "http/1.1"
+	case "h2":
+		return "http/2.0"
+	case "h3":
+		return "http/3.0"
+	default:
+		return protocol
+	}
+}
+
+// formatHARTime はHARの日時形式（ISO 8601、ミリ秒）に変換する
+func formatHARTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
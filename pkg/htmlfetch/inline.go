@@ -0,0 +1,491 @@
+package htmlfetch
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// インライン化のデフォルト上限
+const (
+	defaultInlineMaxAssetSize = 5 << 20  // 1リソースあたり5MB
+	defaultInlineMaxTotalSize = 50 << 20 // 合計50MB
+	inlineMaxDepth            = 3        // iframe・@importの最大ネスト数
+)
+
+// inlineMarker は処理対象の要素に一時的に付与する属性
+const inlineMarker = "data-htmlfetch-inline"
+
+// inlineRef はページから収集したURL参照
+type inlineRef struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// inlineOp はページに適用する書き換え
+type inlineOp struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"` // attr / text / stylesheet
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value"`
+}
+
+// inlineAsset は取得済みのリソース
+type inlineAsset struct {
+	mimeType string
+	data     []byte
+}
+
+// assetInliner はページのサブリソースをdata URIとして埋め込む
+// リソースはブラウザのキャッシュ（Page.getResourceContent）から取得し、
+// 見つからない場合のみブラウザ経由で再取得する（Network.loadNetworkResource）
+type assetInliner struct {
+	page         *rod.Page
+	mainFrame    proto.PageFrameID
+	resources    map[string]*proto.PageFrameResource
+	frames       map[string]proto.PageFrameID
+	cache        map[string]*inlineAsset
+	maxAssetSize int64
+	maxTotalSize int64
+	total        int64
+}
+
+// inlineAssets は画像・フォント・favicon・CSS・同一オリジンのiframeをdata URIとして埋め込み、
+// 残りの相対URLを絶対URLに書き換える
+// 上限を超えるリソースや取得できなかったリソースは絶対URLのまま残す
+func inlineAssets(page *rod.Page, maxAssetSize, maxTotalSize int64) error {
+	tree, err := proto.PageGetResourceTree{}.Call(page)
+	if err != nil {
+		return err
+	}
+
+	in := &assetInliner{
+		page:         page,
+		mainFrame:    tree.FrameTree.Frame.ID,
+		resources:    make(map[string]*proto.PageFrameResource),
+		frames:       make(map[string]proto.PageFrameID),
+		cache:        make(map[string]*inlineAsset),
+		maxAssetSize: maxAssetSize,
+		maxTotalSize: maxTotalSize,
+	}
+	in.indexResources(tree.FrameTree)
+
+	return in.process(page, in.mainFrame, 0)
+}
+
+// indexResources はリソースツリーからURLとフレームの対応を作成する
+func (in *assetInliner) indexResources(tree *proto.PageFrameResourceTree) {
+	for _, r := range tree.Resources {
+		if _, ok := in.resources[r.URL]; !ok {
+			in.resources[r.URL] = r
+			in.frames[r.URL] = tree.Frame.ID
+		}
+	}
+	for _, child := range tree.ChildFrames {
+		in.indexResources(child)
+	}
+}
+
+// process はフレーム内のURL参照を収集し、埋め込み・絶対URL化を適用する
+func (in *assetInliner) process(frame *rod.Page, frameID proto.PageFrameID, depth int) error {
+	res, err := frame.Eval(collectInlineRefsJS, inlineMarker)
+	if err != nil {
+		return err
+	}
+
+	var collected struct {
+		Base string      `json:"base"`
+		Refs []inlineRef `json:"refs"`
+	}
+	if err := res.Value.Unmarshal(&collected); err != nil {
+		return err
+	}
+
+	ops := []inlineOp{}
+	for _, ref := range collected.Refs {
+		switch ref.Kind {
+		case "url":
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: ref.Name, Value: in.inlineURL(collected.Base, ref.Value, frameID)})
+		case "link":
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: ref.Name, Value: resolveURL(collected.Base, ref.Value)})
+		case "srcset":
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: ref.Name, Value: in.inlineSrcset(collected.Base, ref.Value, frameID)})
+		case "style-attr":
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: ref.Name, Value: in.inlineCSS(ref.Value, collected.Base, frameID, depth)})
+		case "style":
+			// WithEmbedCSSで埋め込まれたCSSは元のCSSファイルのURLを基準に解決する
+			base := collected.Base
+			if ref.Name != "" {
+				base = resolveURL(collected.Base, ref.Name)
+			}
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "text", Value: in.inlineCSS(ref.Value, base, frameID, depth)})
+		case "stylesheet":
+			// CSSの本文も合計サイズに含め、上限を超える場合は外部CSSのまま残す
+			href := resolveURL(collected.Base, ref.Value)
+			if asset, err := in.load(href, frameID); err == nil && in.reserve(int64(len(asset.data))) {
+				ops = append(ops, inlineOp{ID: ref.ID, Kind: "stylesheet", Name: href, Value: in.inlineCSS(string(asset.data), href, frameID, depth)})
+			} else {
+				ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: "href", Value: href})
+			}
+		case "frame":
+			ops = append(ops, inlineOp{ID: ref.ID, Kind: "attr", Name: "src", Value: in.inlineFrame(frame, collected.Base, ref, depth)})
+		}
+	}
+
+	_, err = frame.Eval(applyInlineOpsJS, inlineMarker, ops)
+	return err
+}
+
+// inlineURL はURLのリソースをdata URIに変換する。埋め込めない場合は絶対URLを返す
+func (in *assetInliner) inlineURL(base, raw string, frameID proto.PageFrameID) string {
+	abs := resolveURL(base, raw)
+	if !isFetchableURL(abs) {
+		return abs
+	}
+	asset, err := in.load(abs, frameID)
+	if err != nil || !in.reserve(int64(len(asset.data))) {
+		return abs
+	}
+	return dataURI(asset.mimeType, asset.data)
+}
+
+// inlineSrcset はsrcsetの各候補をdata URIに変換する
+func (in *assetInliner) inlineSrcset(base, srcset string, frameID proto.PageFrameID) string {
+	candidates := parseSrcset(srcset)
+	for i, c := range candidates {
+		candidates[i].url = in.inlineURL(base, c.url, frameID)
+	}
+	return formatSrcset(candidates)
+}
+
+// cssImportPattern は @import "x.css" と @import url(x.css) に一致する
+var cssImportPattern = regexp.MustCompile(`@import\s+(?:url\(\s*(['"]?)([^'")]+)['"]?\s*\)|(['"])([^'"]+)['"])`)
+
+// cssURLPattern は url(...) に一致する
+var cssURLPattern = regexp.MustCompile(`url\(\s*(['"]?)([^'")]*)['"]?\s*\)`)
+
+// inlineCSS はCSS内の @import と url() を埋め込む
+func (in *assetInliner) inlineCSS(css, base string, frameID proto.PageFrameID, depth int) string {
+	css = cssImportPattern.ReplaceAllStringFunc(css, func(m string) string {
+		sub := cssImportPattern.FindStringSubmatch(m)
+		raw := sub[2]
+		if raw == "" {
+			raw = sub[4]
+		}
+		abs := resolveURL(base, raw)
+		if depth >= inlineMaxDepth || !isFetchableURL(abs) {
+			return fmt.Sprintf(`@import url("%s")`, abs)
+		}
+		asset, err := in.load(abs, frameID)
+		if err != nil || !in.reserve(int64(len(asset.data))) {
+			return fmt.Sprintf(`@import url("%s")`, abs)
+		}
+		imported := in.inlineCSS(string(asset.data), abs, frameID, depth+1)
+		return fmt.Sprintf(`@import url("%s")`, dataURI("text/css", []byte(imported)))
+	})
+
+	return cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+		raw := cssURLPattern.FindStringSubmatch(m)[2]
+		// data URIとSVGのフラグメント参照（url(#id)）はそのまま
+		if raw == "" || strings.HasPrefix(raw, "data:") || strings.HasPrefix(raw, "#") {
+			return m
+		}
+		return fmt.Sprintf(`url("%s")`, in.inlineURL(base, raw, frameID))
+	})
+}
+
+// inlineFrame は同一オリジンのiframeを再帰的に処理し、その内容をdata URIとして返す
+func (in *assetInliner) inlineFrame(parent *rod.Page, base string, ref inlineRef, depth int) string {
+	abs := resolveURL(base, ref.Value)
+	if depth >= inlineMaxDepth {
+		return abs
+	}
+
+	el, err := parent.Sleeper(rod.NotFoundSleeper).Element(fmt.Sprintf(`[%s="%s"]`, inlineMarker, ref.ID))
+	if err != nil {
+		return abs
+	}
+	frame, err := el.Frame()
+	if err != nil {
+		return abs
+	}
+	if err := in.process(frame, frame.FrameID, depth+1); err != nil {
+		return abs
+	}
+	html, err := frame.HTML()
+	if err != nil {
+		return abs
+	}
+
+	data := []byte("<!DOCTYPE html>\n" + html)
+	if !in.reserve(int64(len(data))) {
+		return abs
+	}
+	return dataURI("text/html;charset=utf-8", data)
+}
+
+// reserve は合計サイズの上限内であれば埋め込むサイズを加算する
+func (in *assetInliner) reserve(size int64) bool {
+	if in.maxTotalSize > 0 && in.total+size > in.maxTotalSize {
+		return false
+	}
+	in.total += size
+	return true
+}
+
+// load はリソースを取得する（同じURLは1回だけ取得する）
+func (in *assetInliner) load(rawURL string, frameID proto.PageFrameID) (*inlineAsset, error) {
+	if asset, ok := in.cache[rawURL]; ok {
+		if asset == nil {
+			return nil, errors.New("取得済みのリソースが利用できません")
+		}
+		return asset, nil
+	}
+
+	asset, err := in.fetch(rawURL, frameID)
+	if err == nil && in.maxAssetSize > 0 && int64(len(asset.data)) > in.maxAssetSize {
+		err = fmt.Errorf("リソースが上限サイズを超えています: %s", rawURL)
+	}
+	if err != nil {
+		in.cache[rawURL] = nil
+		return nil, err
+	}
+	in.cache[rawURL] = asset
+	return asset, nil
+}
+
+// fetch はブラウザのキャッシュ、またはブラウザ経由のリクエストでリソースを取得する
+func (in *assetInliner) fetch(rawURL string, frameID proto.PageFrameID) (*inlineAsset, error) {
+	if r, ok := in.resources[rawURL]; ok {
+		content, err := proto.PageGetResourceContent{FrameID: in.frames[rawURL], URL: rawURL}.Call(in.page)
+		if err == nil {
+			data := []byte(content.Content)
+			if content.Base64Encoded {
+				if data, err = base64.StdEncoding.DecodeString(content.Content); err != nil {
+					return nil, err
+				}
+			}
+			return &inlineAsset{mimeType: assetMIMEType(r.MIMEType, rawURL, data), data: data}, nil
+		}
+	}
+
+	if frameID == "" {
+		frameID = in.mainFrame
+	}
+	res, err := proto.NetworkLoadNetworkResource{
+		FrameID: frameID,
+		URL:     rawURL,
+		Options: &proto.NetworkLoadNetworkResourceOptions{IncludeCredentials: true},
+	}.Call(in.page)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Resource.Success || res.Resource.Stream == "" {
+		return nil, fmt.Errorf("リソースの取得に失敗しました: %s (%s)", rawURL, res.Resource.NetErrorName)
+	}
+
+	stream := rod.NewStreamReader(in.page, res.Resource.Stream)
+	defer stream.Close()
+
+	var reader io.Reader = stream
+	if in.maxAssetSize > 0 {
+		reader = io.LimitReader(stream, in.maxAssetSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var contentType string
+	for k, v := range res.Resource.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = v.Str()
+		}
+	}
+	return &inlineAsset{mimeType: assetMIMEType(contentType, rawURL, data), data: data}, nil
+}
+
+// assetMIMEType はContent-Type・拡張子・内容の順にMIMEタイプを判定する
+func assetMIMEType(contentType, rawURL string, data []byte) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil && mt != "" {
+		return mt
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		if mt := mime.TypeByExtension(path.Ext(u.Path)); mt != "" {
+			mt, _, _ = strings.Cut(mt, ";")
+			return mt
+		}
+	}
+	mt, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return mt
+}
+
+// dataURI はdata URIを作成する
+func dataURI(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// resolveURL はbaseを基準に相対URLを絶対URLに変換する
+func resolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// isFetchableURL は埋め込み対象として取得できるURLかを返す
+func isFetchableURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")
+}
+
+// srcsetCandidate はsrcsetの1候補
+type srcsetCandidate struct {
+	url        string
+	descriptor string
+}
+
+// parseSrcset はsrcset属性を候補のリストに分解する
+// URLにカンマを含むdata URIも扱えるよう、HTML仕様の分割アルゴリズムに従う
+func parseSrcset(srcset string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\r\f,")
+		if s == "" {
+			return candidates
+		}
+
+		end := strings.IndexAny(s, " \t\n\r\f")
+		if end == -1 {
+			end = len(s)
+		}
+		u := s[:end]
+		s = s[end:]
+
+		var descriptor string
+		if strings.HasSuffix(u, ",") {
+			u = strings.TrimRight(u, ",")
+		} else {
+			descriptor, s, _ = strings.Cut(s, ",")
+			descriptor = strings.TrimSpace(descriptor)
+		}
+		candidates = append(candidates, srcsetCandidate{url: u, descriptor: descriptor})
+	}
+}
+
+// formatSrcset は候補のリストをsrcset属性の値に戻す
+func formatSrcset(candidates []srcsetCandidate) string {
+	parts := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if c.descriptor != "" {
+			parts = append(parts, c.url+" "+c.descriptor)
+		} else {
+			parts = append(parts, c.url)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// collectInlineRefsJS はURLを参照する要素に目印の属性を付け、参照の一覧を返す
+const collectInlineRefsJS = `(marker) => {
+	const refs = [];
+	let seq = 0;
+	const add = (el, kind, name, value) => {
+		if (value === null || value === undefined || value === '') return;
+		if (!el.hasAttribute(marker)) el.setAttribute(marker, String(seq++));
+		refs.push({id: el.getAttribute(marker), kind: kind, name: name, value: value});
+	};
+
+	// 埋め込む画像・favicon
+	document.querySelectorAll('img, source, input[type="image"]').forEach(el => {
+		add(el, 'url', 'src', el.getAttribute('src'));
+		add(el, 'srcset', 'srcset', el.getAttribute('srcset'));
+	});
+	document.querySelectorAll('video[poster]').forEach(el => add(el, 'url', 'poster', el.getAttribute('poster')));
+	document.querySelectorAll('link[rel~="icon"], link[rel="apple-touch-icon"]').forEach(el => {
+		add(el, 'url', 'href', el.getAttribute('href'));
+	});
+
+	// CSS
+	document.querySelectorAll('link[rel~="stylesheet"][href]').forEach(el => {
+		if (el.disabled) return;
+		add(el, 'stylesheet', 'href', el.getAttribute('href'));
+	});
+	document.querySelectorAll('style').forEach(el => {
+		add(el, 'style', el.getAttribute('data-original-href') || '', el.textContent);
+	});
+	document.querySelectorAll('[style*="url("]').forEach(el => {
+		add(el, 'style-attr', 'style', el.getAttribute('style'));
+	});
+
+	// 同一オリジンのiframeは中身を埋め込み、それ以外は絶対URLにする
+	document.querySelectorAll('iframe').forEach(el => {
+		let sameOrigin = false;
+		try { sameOrigin = !!el.contentDocument; } catch (e) {}
+		if (sameOrigin && !el.hasAttribute('srcdoc')) {
+			add(el, 'frame', 'src', el.getAttribute('src') || 'about:blank');
+		} else {
+			add(el, 'link', 'src', el.getAttribute('src'));
+		}
+	});
+
+	// 埋め込まないリンクは絶対URLにする
+	const links = [
+		['a[href], area[href]', 'href'],
+		['form[action]', 'action'],
+		['script[src], audio[src], video[src], embed[src], track[src]', 'src'],
+		['object[data]', 'data'],
+		['link[href]:not([rel~="stylesheet"]):not([rel~="icon"]):not([rel="apple-touch-icon"])', 'href'],
+	];
+	links.forEach(([selector, name]) => {
+		document.querySelectorAll(selector).forEach(el => {
+			const value = el.getAttribute(name);
+			if (value && !/^(#|javascript:|data:|mailto:|tel:)/i.test(value)) add(el, 'link', name, value);
+		});
+	});
+
+	return {base: document.baseURI, refs: refs};
+}`
+
+// applyInlineOpsJS は書き換えを適用し、目印の属性を取り除く
+const applyInlineOpsJS = `(marker, ops) => {
+	for (const op of ops) {
+		const el = document.querySelector('[' + marker + '="' + op.id + '"]');
+		if (!el) continue;
+		switch (op.kind) {
+		case 'attr':
+			el.setAttribute(op.name, op.value);
+			break;
+		case 'text':
+			el.textContent = op.value;
+			break;
+		case 'stylesheet': {
+			const style = document.createElement('style');
+			if (el.media) style.media = el.media;
+			style.setAttribute('data-original-href', op.name);
+			style.textContent = op.value;
+			el.replaceWith(style);
+			break;
+		}
+		}
+	}
+	document.querySelectorAll('[' + marker + ']').forEach(el => el.removeAttribute(marker));
+}`
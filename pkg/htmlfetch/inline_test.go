@@ -0,0 +1,45 @@
+package htmlfetch
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseSrcset はsrcsetの分割が記述子やカンマを含むdata URIを正しく扱うことを検証する。
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []srcsetCandidate
+	}{
+		{
+			name:   "descriptors",
+			srcset: "a.png 1x, b.png 2x",
+			want:   []srcsetCandidate{{"a.png", "1x"}, {"b.png", "2x"}},
+		},
+		{
+			name:   "no_spaces",
+			srcset: "a.png 480w,b.png 800w",
+			want:   []srcsetCandidate{{"a.png", "480w"}, {"b.png", "800w"}},
+		},
+		{
+			name:   "without_descriptor",
+			srcset: "a.png, b.png 2x",
+			want:   []srcsetCandidate{{"a.png", ""}, {"b.png", "2x"}},
+		},
+		{
+			name:   "data_uri_with_comma",
+			srcset: "data:image/png;base64,AAAA 1x, b.png 2x",
+			want:   []srcsetCandidate{{"data:image/png;base64,AAAA", "1x"}, {"b.png", "2x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSrcset(tt.srcset)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSrcset(%q) = %v, want %v", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
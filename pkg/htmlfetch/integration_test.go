@@ -1,10 +1,25 @@
 package htmlfetch
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
 )
 
 // TestFetchDynamicContent は動的コンテンツの取得を各待機戦略でテストする。
@@ -152,9 +167,9 @@ func TestUserAgent_NoHeadlessChrome(t *testing.T) {
 	}
 }
 
-// TestStealth_BotDetection はstealth有効時にbot検出チェックをパスすることを検証する。
-// 各チェック項目の詳細はtestserver_test.goのbotDetectPageコメントを参照。
-func TestStealth_BotDetection(t *testing.T) {
+// TestRequestHeaderOverrides はWithHeaders・WithUserAgent・WithAcceptLanguageが
+// リクエストに反映され、プールモードで次のFetchに残らないことを検証する。
+func TestRequestHeaderOverrides(t *testing.T) {
 	if testing.Short() {
 		t.Skip("統合テストをスキップ（-short指定）")
 	}
@@ -162,131 +177,1673 @@ func TestStealth_BotDetection(t *testing.T) {
 	ts := newTestServer(t)
 	defer ts.Close()
 
-	fetcher := New(WithStealth(true))
+	fetcher := New(WithStealth(false), WithMaxConcurrentPages(1))
 	if err := fetcher.Start(); err != nil {
 		t.Fatalf("ブラウザの起動に失敗: %v", err)
 	}
 	defer fetcher.Close()
 
-	result, err := fetcher.Fetch(context.Background(), ts.URL+"/bot-detect",
-		WithWaitStrategy(WaitDOMStable))
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/echo-headers",
+		WithHeaders(map[string]string{"X-Test-Header": "HEADER_MARKER"}),
+		WithUserAgent("CustomAgent/1.0"),
+		WithAcceptLanguage("en-US"))
 	if err != nil {
 		t.Fatalf("Fetchに失敗: %v", err)
 	}
+	assertContains(t, result.HTML, `<p id="custom">HEADER_MARKER</p>`)
+	assertContains(t, result.HTML, `<p id="ua">CustomAgent/1.0</p>`)
+	assertContains(t, result.HTML, `<p id="lang">en-US</p>`)
 
-	// <pre id="results">...</pre> からJSONを抽出
+	// 再利用されたタブには前回のヘッダーが残らない
+	result, err = fetcher.Fetch(context.Background(), ts.URL+"/echo-headers")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	if strings.Contains(result.HTML, "HEADER_MARKER") {
+		t.Error("前回のFetchの追加ヘッダーが残っています")
+	}
+	assertContains(t, result.HTML, `<p id="lang">ja</p>`)
+
+	// Accept-Languageを英語にすると英語ページにリダイレクトされる
+	result, err = fetcher.Fetch(context.Background(), ts.URL+"/lang-redirect",
+		WithAcceptLanguage("en"))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	assertContains(t, result.HTML, "LANG_EN_MARKER")
+}
+
+// TestDeviceEmulation はWithDeviceで画面サイズ・解像度・タッチ・UA・platformが
+// エミュレートされることを検証する。
+func TestDeviceEmulation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/device-info",
+		WithDevice(DevicePixel))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	var info struct {
+		Width    int     `json:"width"`
+		DPR      float64 `json:"dpr"`
+		Touch    int     `json:"touch"`
+		UA       string  `json:"ua"`
+		Platform string  `json:"platform"`
+	}
 	start := strings.Index(result.HTML, `<pre id="results">`)
 	end := strings.Index(result.HTML, `</pre>`)
 	if start == -1 || end == -1 {
 		t.Fatalf("結果のHTMLからJSONを抽出できません (HTML長: %d)", len(result.HTML))
 	}
-	jsonStr := result.HTML[start+len(`<pre id="results">`):end]
-
-	var results map[string]string
-	if err := json.Unmarshal([]byte(jsonStr), &results); err != nil {
+	jsonStr := result.HTML[start+len(`<pre id="results">`) : end]
+	if err := json.Unmarshal([]byte(jsonStr), &info); err != nil {
 		t.Fatalf("結果JSONのパースに失敗: %v\nJSON: %s", err, jsonStr)
 	}
 
-	// stealth対策済みのチェック項目（PASSが期待される）
-	mustPass := []struct {
-		key  string
-		desc string
-	}{
-		{"webdriver", "navigator.webdriver が false/undefined"},
-		{"chrome_exists", "window.chrome が存在する"},
-		{"chrome_app", "window.chrome.app が存在する"},
-		{"chrome_csi", "window.chrome.csi が関数として存在する"},
-		{"chrome_loadTimes", "window.chrome.loadTimes が関数として存在する"},
-		{"plugins", "navigator.plugins が空でない"},
-		{"languages", "navigator.languages が空でない"},
-		{"outer_dimensions", "window.outerWidth/Height が 0 でない"},
-		{"user_agent", "User-Agentに HeadlessChrome が含まれない"},
+	if info.Width != DevicePixel.Width {
+		t.Errorf("innerWidth: got %d, want %d", info.Width, DevicePixel.Width)
+	}
+	if info.DPR != DevicePixel.DeviceScaleFactor {
+		t.Errorf("devicePixelRatio: got %v, want %v", info.DPR, DevicePixel.DeviceScaleFactor)
+	}
+	if info.Touch == 0 {
+		t.Error("タッチ操作がエミュレートされていません")
 	}
+	if !strings.Contains(info.UA, "Pixel 8") || strings.Contains(info.UA, "{version}") {
+		t.Errorf("UserAgentがデバイスのものになっていません: %s", info.UA)
+	}
+	if info.Platform != DevicePixel.Platform {
+		t.Errorf("navigator.platform: got %q, want %q", info.Platform, DevicePixel.Platform)
+	}
+}
 
-	for _, tc := range mustPass {
-		t.Run(tc.key, func(t *testing.T) {
-			v, ok := results[tc.key]
-			if !ok {
-				t.Errorf("チェック項目 %q が結果に含まれていません", tc.key)
-				return
+// TestEnvironmentEmulation はタイムゾーン・ロケール・位置情報のエミュレーションと、
+// 位置情報の権限がFetch後に取り消されることを検証する。
+func TestEnvironmentEmulation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	for _, isolated := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Isolated=%v", isolated), func(t *testing.T) {
+			opts := []FetchOption{
+				WithTimezone("Asia/Tokyo"),
+				WithLocale("ja-JP"),
+				WithGeolocation(35.6812, 139.7671, 10),
+				WithSelector("#done", 10*time.Second),
 			}
-			if v != "PASS" {
-				t.Errorf("%s: %s (got %s)", tc.key, tc.desc, v)
+			if isolated {
+				opts = append(opts, WithIsolatedContext())
+			}
+			result, err := fetcher.Fetch(context.Background(), ts.URL+"/env-info", opts...)
+			if err != nil {
+				t.Fatalf("Fetchに失敗: %v", err)
+			}
+
+			info := parseEnvInfo(t, result.HTML)
+
+			if info.Timezone != "Asia/Tokyo" {
+				t.Errorf("タイムゾーン: got %q, want %q", info.Timezone, "Asia/Tokyo")
+			}
+			if info.Locale != "ja-JP" {
+				t.Errorf("ロケール: got %q, want %q", info.Locale, "ja-JP")
+			}
+			if info.GeoError != "" {
+				t.Fatalf("位置情報の取得に失敗: %s", info.GeoError)
+			}
+			if info.Latitude != 35.6812 || info.Longitude != 139.7671 {
+				t.Errorf("位置情報: got (%v, %v)", info.Latitude, info.Longitude)
 			}
 		})
 	}
 
-	// SKIPを許容するチェック項目（環境依存）
-	maySkip := []struct {
-		key  string
-		desc string
-	}{
-		{"notification_permission", "Notification.permission が denied でない"},
-		{"webgl", "WebGL renderer に SwiftShader が含まれない"},
-		{"fn_toString", "Permissions.prototype.query.toString() が [native code] を返す"},
+	// WithGeolocationを指定しないFetchには、前のFetchで付与した権限が残らない
+	t.Run("permission_revoked", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/env-info", WithSelector("#done", 10*time.Second))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		info := parseEnvInfo(t, result.HTML)
+		if info.GeoPermission == "granted" {
+			t.Errorf("位置情報の権限が残っています: %+v", info)
+		}
+	})
+}
+
+// TestEnvironmentEmulation_ColdConcurrent はコールドモードで同時に実行した
+// WithGeolocationのFetchそれぞれのブラウザに位置情報の権限が付与されることを検証する。
+func TestEnvironmentEmulation_ColdConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
 	}
 
-	for _, tc := range maySkip {
-		t.Run(tc.key, func(t *testing.T) {
-			v, ok := results[tc.key]
-			if !ok {
-				t.Skipf("チェック項目 %q が結果に含まれていません", tc.key)
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	// Startを呼ばないため、Fetchごとにブラウザを起動する
+	fetcher := New(WithStealth(false))
+	defer fetcher.Close()
+
+	const n = 2
+	htmls := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/env-info?n=%d", ts.URL, i),
+				WithGeolocation(35.6812, 139.7671, 10),
+				WithSelector("#done", 10*time.Second))
+			if err != nil {
+				errs[i] = err
 				return
 			}
-			switch v {
-			case "PASS":
-				// OK
-			case "SKIP":
-				t.Skipf("%s: 環境依存でスキップ", tc.key)
-			default:
-				t.Errorf("%s: %s (got %s)", tc.key, tc.desc, v)
+			htmls[i] = result.HTML
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("Fetch %dに失敗: %v", i, errs[i])
+		}
+		info := parseEnvInfo(t, htmls[i])
+		if info.GeoPermission != "granted" || info.GeoError != "" {
+			t.Errorf("Fetch %d: 位置情報の権限が付与されていません: %+v", i, info)
+		}
+	}
+}
+
+// envInfo はenv-infoページの結果
+type envInfo struct {
+	Timezone      string  `json:"timezone"`
+	Locale        string  `json:"locale"`
+	GeoPermission string  `json:"geo_permission"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	GeoError      string  `json:"geo_error"`
+}
+
+// parseEnvInfo はenv-infoページのHTMLから結果のJSONを取り出す
+func parseEnvInfo(t *testing.T, html string) envInfo {
+	t.Helper()
+	var info envInfo
+	start := strings.Index(html, `<pre id="results">`)
+	end := strings.Index(html, `</pre>`)
+	if start == -1 || end == -1 {
+		t.Fatalf("結果のHTMLからJSONを抽出できません (HTML長: %d)", len(html))
+	}
+	jsonStr := html[start+len(`<pre id="results">`) : end]
+	if err := json.Unmarshal([]byte(jsonStr), &info); err != nil {
+		t.Fatalf("結果JSONのパースに失敗: %v\nJSON: %s", err, jsonStr)
+	}
+	return info
+}
+
+// TestScreenshot はビューポート・ページ全体・要素のスクリーンショットをテストする。
+func TestScreenshot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	tests := []struct {
+		name       string
+		opts       ScreenshotOptions
+		wantFormat string
+		wantWidth  int
+		wantHeight int
+	}{
+		{"viewport", ScreenshotOptions{}, "png", 800, 600},
+		// ページ全体の幅はスクロールバーを除いたコンテンツ幅になるため高さのみ確認する
+		{"full_page", ScreenshotOptions{Format: ScreenshotJPEG, Quality: 80, FullPage: true}, "jpeg", 0, 3100},
+		{"selector", ScreenshotOptions{Selector: "#box"}, "png", 200, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fetcher.Fetch(context.Background(), ts.URL+"/tall",
+				WithViewport(800, 600),
+				WithScreenshot(tt.opts))
+			if err != nil {
+				t.Fatalf("Fetchに失敗: %v", err)
+			}
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(result.Screenshot))
+			if err != nil {
+				t.Fatalf("画像のデコードに失敗: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("形式: got %s, want %s", format, tt.wantFormat)
+			}
+			if (tt.wantWidth > 0 && cfg.Width != tt.wantWidth) || cfg.Height != tt.wantHeight {
+				t.Errorf("サイズ: got %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantWidth, tt.wantHeight)
 			}
 		})
 	}
 
-	// 全結果をログに出力（デバッグ用）
-	t.Logf("bot検出結果: %s", jsonStr)
+	t.Run("selector_not_found", func(t *testing.T) {
+		_, err := fetcher.Fetch(context.Background(), ts.URL+"/tall",
+			WithScreenshot(ScreenshotOptions{Selector: "#missing"}))
+		var fe *FetchError
+		if !errors.As(err, &fe) || fe.Code != ErrCaptureFailed {
+			t.Errorf("CAPTURE_FAILEDエラーを期待しましたが: %v", err)
+		}
+	})
 }
 
-// TestIgnoreCertErrors は自己署名証明書のHTTPSサーバーに対する動作を検証する。
-func TestIgnoreCertErrors(t *testing.T) {
+// TestPDF はWithPDFでPDFが生成され、用紙サイズと向きが反映されることを検証する。
+func TestPDF(t *testing.T) {
 	if testing.Short() {
 		t.Skip("統合テストをスキップ（-short指定）")
 	}
 
-	ts := newTLSTestServer(t)
+	ts := newTestServer(t)
 	defer ts.Close()
 
-	t.Run("WithoutOption_Fails", func(t *testing.T) {
-		fetcher := New(WithStealth(false))
-		if err := fetcher.Start(); err != nil {
-			t.Fatalf("ブラウザの起動に失敗: %v", err)
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/",
+		WithPDF(PDFOptions{
+			Paper:          PaperA4,
+			Landscape:      true,
+			Margin:         &PDFMargin{Top: 0.5, Bottom: 0.5, Left: 0.5, Right: 0.5},
+			FooterTemplate: `<div style="font-size:8px"><span class="pageNumber"></span></div>`,
+		}))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	if !bytes.HasPrefix(result.PDF, []byte("%PDF-")) {
+		t.Fatalf("PDFのシグネチャがありません (先頭: %q)", result.PDF[:min(len(result.PDF), 16)])
+	}
+	// A4横向き: 11.69 x 8.27 インチ = 約841.9 x 595.4 ポイント
+	if !bytes.Contains(result.PDF, []byte("/MediaBox [0 0 841.9")) {
+		t.Error("用紙サイズがA4横向きになっていません")
+	}
+	if result.HTML == "" {
+		t.Error("PDF出力時もHTMLが取得されるべき")
+	}
+}
+
+// TestMHTML はWithMHTMLで画像とiframeを含むアーカイブが保存されることを検証する。
+func TestMHTML(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/", WithMHTML())
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	assertContains(t, result.MHTML, "multipart/related")
+	assertContains(t, result.MHTML, "Content-Location: "+ts.URL+"/assets/pixel.png")
+	assertContains(t, result.MHTML, "Content-Location: "+ts.URL+"/assets/style.css")
+	assertContains(t, result.MHTML, "FRAME_MARKER")
+}
+
+// TestInlineAssets はWithInlineAssetsで画像・CSS・フォント・iframeがdata URIとして埋め込まれ、
+// リンクが絶対URLに書き換えられることを検証する。
+func TestInlineAssets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("inline", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/", WithInlineAssets())
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
 		}
-		defer fetcher.Close()
 
-		_, err := fetcher.Fetch(context.Background(), ts.URL+"/",
-			WithWaitStrategy(WaitLoad))
-		if err == nil {
-			t.Error("証明書エラーが発生するはずですが、成功しました")
+		assertContains(t, result.HTML, `src="data:image/png;base64,`)
+		assertContains(t, result.HTML, `srcset="data:image/png;base64,`)
+		assertContains(t, result.HTML, `url("data:image/png;base64,`)
+		assertContains(t, result.HTML, `url("data:font/woff2;base64,`)
+		assertContains(t, result.HTML, `href="`+ts.URL+`/"`)
+		if strings.Contains(result.HTML, `<link rel="stylesheet"`) {
+			t.Error("外部CSSのlink要素が残っています")
+		}
+		if strings.Contains(result.HTML, inlineMarker) {
+			t.Error("処理用の属性が残っています")
+		}
+
+		// iframeの中身もdata URIとして埋め込まれる
+		const prefix = `src="data:text/html;charset=utf-8;base64,`
+		start := strings.Index(result.HTML, prefix)
+		if start == -1 {
+			t.Fatal("iframeがdata URIになっていません")
+		}
+		encoded := result.HTML[start+len(prefix):]
+		encoded = encoded[:strings.Index(encoded, `"`)]
+		frame, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("iframeのデコードに失敗: %v", err)
 		}
+		assertContains(t, string(frame), "FRAME_MARKER")
 	})
 
-	t.Run("WithOption_Succeeds", func(t *testing.T) {
-		fetcher := New(WithStealth(false), WithIgnoreCertErrors(true))
-		if err := fetcher.Start(); err != nil {
-			t.Fatalf("ブラウザの起動に失敗: %v", err)
+	t.Run("size_limit", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/",
+			WithInlineAssets(),
+			WithInlineAssetLimits(10, 1<<20))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
 		}
-		defer fetcher.Close()
 
-		result, err := fetcher.Fetch(context.Background(), ts.URL+"/",
-			WithWaitStrategy(WaitLoad))
+		// 上限を超える画像は絶対URLのまま残る
+		assertContains(t, result.HTML, `src="`+ts.URL+`/assets/pixel.png"`)
+		if strings.Contains(result.HTML, "data:image/png") {
+			t.Error("上限を超える画像が埋め込まれています")
+		}
+	})
+
+	t.Run("total_limit", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/",
+			WithInlineAssets(),
+			WithInlineAssetLimits(1<<20, 10))
 		if err != nil {
 			t.Fatalf("Fetchに失敗: %v", err)
 		}
-		assertContains(t, result.HTML, "STATIC_CONTENT_MARKER")
+
+		// 合計の上限を超えるCSSは埋め込まず、外部CSSのまま残る
+		assertContains(t, result.HTML, `href="`+ts.URL+`/assets/style.css"`)
+		if strings.Contains(result.HTML, "@font-face") {
+			t.Error("合計の上限を超えるCSSが埋め込まれています")
+		}
 	})
 }
 
+// TestWARC はWithWARCでページのリクエスト・レスポンスと描画後のDOMが記録されることを検証する。
+func TestWARC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	fetcher := New(WithStealth(false))
+	if _, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/", WithWARC(&buf)); err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	responses := make(map[string]string)
+	var last warcRecord
+	for _, r := range readWARCRecords(t, buf.Bytes()) {
+		if r.headers.Get("WARC-Type") == "response" {
+			responses[r.headers.Get("WARC-Target-URI")] = string(r.block)
+		}
+		last = r
+	}
+
+	assertContains(t, responses[ts.URL+"/assets/"], "ASSETS_MARKER")
+	assertContains(t, responses[ts.URL+"/assets/style.css"], "@font-face")
+	if !strings.Contains(responses[ts.URL+"/assets/pixel.png"], "Content-Type: image/png") {
+		t.Errorf("画像のresponseレコードがありません (記録されたURL: %d件)", len(responses))
+	}
+	if last.headers.Get("WARC-Type") != "resource" {
+		t.Fatalf("最後のレコードが描画後のDOMではありません: %s", last.headers.Get("WARC-Type"))
+	}
+	assertContains(t, string(last.block), "ASSETS_MARKER")
+}
+
+// TestHAR はWithHARでリダイレクト・ブロックを含む全リクエストが記録されることを検証する。
+func TestHAR(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("blocked", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/",
+			WithBlocking(BlockingOptions{Image: true}),
+			WithHAR())
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if result.HAR == nil {
+			t.Fatal("Result.HARがnilです")
+		}
+
+		entries := make(map[string]HAREntry)
+		for _, e := range result.HAR.Log.Entries {
+			entries[e.Request.URL] = e
+		}
+
+		doc := entries[ts.URL+"/assets/"]
+		if doc.Response.Status != 200 || doc.Response.Content.MimeType != "text/html" {
+			t.Errorf("ドキュメント: status=%d mimeType=%q", doc.Response.Status, doc.Response.Content.MimeType)
+		}
+		if headerValue(doc.Response.Headers, "Content-Type") == "" {
+			t.Error("レスポンスヘッダーが記録されていません")
+		}
+		if doc.Timings.Wait < 0 || doc.Time <= 0 {
+			t.Errorf("タイミングが記録されていません: %+v", doc.Timings)
+		}
+		if doc.Request.HeadersSize <= 0 || doc.Response.HeadersSize <= 0 {
+			t.Errorf("ヘッダーのサイズ: request=%d response=%d", doc.Request.HeadersSize, doc.Response.HeadersSize)
+		}
+
+		img := entries[ts.URL+"/assets/pixel.png"]
+		if img.Response.Status != 0 || !strings.Contains(img.Error, "ERR_BLOCKED_BY_CLIENT") {
+			t.Errorf("ブロックした画像: status=%d _error=%q", img.Response.Status, img.Error)
+		}
+		if img.BlockedBy != "Image" {
+			t.Errorf("ブロックした画像: _blockedBy=%q, want %q", img.BlockedBy, "Image")
+		}
+
+		if _, err := json.Marshal(result.HAR); err != nil {
+			t.Errorf("HARのJSON変換に失敗: %v", err)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/lang-redirect", WithHAR())
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+
+		entries := result.HAR.Log.Entries
+		if len(entries) < 2 {
+			t.Fatalf("エントリ数: got %d, want >= 2", len(entries))
+		}
+		if entries[0].Response.Status != 302 || entries[0].Response.RedirectURL != ts.URL+"/lang-redirect/ja" {
+			t.Errorf("リダイレクト: status=%d redirectURL=%q", entries[0].Response.Status, entries[0].Response.RedirectURL)
+		}
+		if entries[1].Request.URL != ts.URL+"/lang-redirect/ja" || entries[1].Response.Status != 200 {
+			t.Errorf("リダイレクト先: url=%q status=%d", entries[1].Request.URL, entries[1].Response.Status)
+		}
+	})
+}
+
+// TestCaptureResponses はJSが読み込んだAPIレスポンスのボディが
+// 条件に応じてResult.Responsesに記録されることを検証する。
+func TestCaptureResponses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("filter", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/handlebars",
+			WithWaitStrategy(WaitAuto),
+			WithCaptureResponses(ResponseFilter{
+				URLGlob:       "*/api/*",
+				ResourceTypes: []string{"XHR", "Fetch"},
+				MIMETypes:     []string{"application/json"},
+			}))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if len(result.Responses) != 1 {
+			t.Fatalf("記録したレスポンス数: got %d, want 1", len(result.Responses))
+		}
+
+		res := result.Responses[0]
+		if res.URL != ts.URL+"/api/handlebars-data" || res.Status != 200 || res.Method != "GET" {
+			t.Errorf("レスポンス: url=%q status=%d method=%q", res.URL, res.Status, res.Method)
+		}
+		if res.Headers.Get("Content-Type") != "application/json" || res.MIMEType != "application/json" {
+			t.Errorf("Content-Type: header=%q mimeType=%q", res.Headers.Get("Content-Type"), res.MIMEType)
+		}
+		if string(res.Body) != handlebarsData || res.Truncated {
+			t.Errorf("ボディ: got %q (truncated=%v)", res.Body, res.Truncated)
+		}
+	})
+
+	t.Run("max_body_size", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/handlebars",
+			WithWaitStrategy(WaitAuto),
+			WithCaptureResponses(ResponseFilter{
+				URLRegexp:   regexp.MustCompile(`/api/handlebars-data$`),
+				MaxBodySize: 10,
+			}))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if len(result.Responses) != 1 {
+			t.Fatalf("記録したレスポンス数: got %d, want 1", len(result.Responses))
+		}
+		if res := result.Responses[0]; len(res.Body) != 10 || !res.Truncated {
+			t.Errorf("上限で切り詰められていません: len=%d truncated=%v", len(res.Body), res.Truncated)
+		}
+	})
+}
+
+// TestConsoleCapture はconsole.log・未捕捉の例外・リソースの読み込みエラーが
+// Result.Consoleに記録されることを検証する。
+func TestConsoleCapture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/console",
+		WithWaitStrategy(WaitNetworkIdle),
+		WithConsoleCapture())
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	find := func(substr string) *ConsoleMessage {
+		for i := range result.Console {
+			if strings.Contains(result.Console[i].Text, substr) {
+				return &result.Console[i]
+			}
+		}
+		t.Errorf("%qを含むメッセージがありません: %+v", substr, result.Console)
+		return nil
+	}
+
+	if m := find("CONSOLE_LOG_MARKER"); m != nil {
+		if m.Level != ConsoleLevelLog || m.Source != ConsoleSourceConsoleAPI {
+			t.Errorf("console.log: level=%q source=%q", m.Level, m.Source)
+		}
+		if m.Text != "CONSOLE_LOG_MARKER 42 Object" {
+			t.Errorf("console.logの引数: got %q", m.Text)
+		}
+		if m.URL != ts.URL+"/console" || m.Line != 6 {
+			t.Errorf("console.logの位置: url=%q line=%d", m.URL, m.Line)
+		}
+	}
+	if m := find("CONSOLE_WARN_MARKER"); m != nil && m.Level != ConsoleLevelWarning {
+		t.Errorf("console.warn: level=%q", m.Level)
+	}
+	if m := find("UNCAUGHT_ERROR_MARKER"); m != nil {
+		if m.Level != ConsoleLevelError || m.Source != ConsoleSourceException {
+			t.Errorf("例外: level=%q source=%q", m.Level, m.Source)
+		}
+		if m.Text != "Uncaught Error: UNCAUGHT_ERROR_MARKER" || m.Line != 10 {
+			t.Errorf("例外: text=%q line=%d", m.Text, m.Line)
+		}
+	}
+	if m := find("404"); m != nil && (m.Level != ConsoleLevelError || m.Source != "network") {
+		t.Errorf("読み込みエラー: level=%q source=%q", m.Level, m.Source)
+	}
+
+	// 指定しない場合は収集しない
+	result, err = fetcher.Fetch(context.Background(), ts.URL+"/console")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	if len(result.Console) != 0 {
+		t.Errorf("WithConsoleCapture未指定でも記録されています: %+v", result.Console)
+	}
+}
+
+// TestNetworkStats_Failures はブロック・読み込みに失敗したリクエストが
+// 理由とルール付きでNetworkStatsに記録されることを検証する。
+func TestNetworkStats_Failures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/failures",
+		WithBlocking(BlockingOptions{Image: true}))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	stats := result.Stats
+
+	if stats.BlockedCount != 2 || stats.BlockedByRule["Image"] != 2 {
+		t.Errorf("ブロック数: total=%d byRule=%v", stats.BlockedCount, stats.BlockedByRule)
+	}
+	if img := stats.ByResourceType["Image"]; img == nil || img.Blocked != 2 || img.Count != 0 {
+		t.Errorf("Imageの統計: %+v", img)
+	}
+	if stats.EstimatedBytesSaved != 2*typicalResourceSize["Image"] {
+		t.Errorf("削減量の推定: got %d", stats.EstimatedBytesSaved)
+	}
+
+	failures := make(map[string]RequestFailure)
+	for _, f := range stats.Failures {
+		failures[f.URL] = f
+	}
+	if f := failures[ts.URL+"/assets/pixel.png"]; f.Reason != FailureBlocked || f.BlockedBy != "Image" || f.ResourceType != "Image" {
+		t.Errorf("ブロックした画像: %+v", f)
+	}
+	if f := failures["http://nonexistent.invalid/app.js"]; f.Reason != FailureDNS || f.ResourceType != "Script" {
+		t.Errorf("名前解決できないスクリプト: %+v", f)
+	}
+	if f := failures["http://127.0.0.1:2/refused.css"]; f.Reason != FailureConnection || f.BlockedBy != "" {
+		t.Errorf("接続できないCSS: %+v", f)
+	}
+}
+
+// TestNetworkStats_Bytes は送受信量が実際のヘッダーから計算され、
+// リダイレクト・キャッシュから返されたレスポンスが区別されることを検証する。
+func TestNetworkStats_Bytes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("redirect", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/lang-redirect")
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		stats := result.Stats
+
+		if stats.RedirectCount != 1 || stats.RedirectBytesIn <= 0 {
+			t.Errorf("リダイレクト: count=%d bytesIn=%d", stats.RedirectCount, stats.RedirectBytesIn)
+		}
+		if stats.RequestCount != 2 {
+			t.Errorf("リクエスト数: got %d, want 2", stats.RequestCount)
+		}
+		// ボディのないGETの送信量は、リクエスト行とヘッダーのみ
+		if stats.HeaderBytesOut <= 0 || stats.TotalBytesOut != stats.HeaderBytesOut {
+			t.Errorf("送信量: total=%d header=%d", stats.TotalBytesOut, stats.HeaderBytesOut)
+		}
+		if stats.HeaderBytesIn <= 0 || stats.TotalBytesIn <= stats.HeaderBytesIn {
+			t.Errorf("受信量: total=%d header=%d", stats.TotalBytesIn, stats.HeaderBytesIn)
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		// 画像の読み込み後、同じURLをfetchで再取得する（2回目はキャッシュから返される）
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/cacheable",
+			WithSelector("#refetched", 5*time.Second))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		stats := result.Stats
+
+		if stats.CacheHits != 1 {
+			t.Errorf("キャッシュヒット数: got %d, want 1", stats.CacheHits)
+		}
+		if img := stats.ByResourceType["Image"]; img == nil || img.BytesIn < int64(len(pixelPNG)) {
+			t.Errorf("画像の受信量: %+v", img)
+		}
+		if f := stats.ByResourceType["Fetch"]; f == nil || f.Count != 1 || f.BytesIn != 0 || f.BytesOut != 0 {
+			t.Errorf("キャッシュから返されたfetchが通信量に含まれています: %+v", f)
+		}
+	})
+}
+
+// TestTiming はFetchの処理時間の内訳とページのパフォーマンス指標が
+// Result.Timingに記録されることを検証する。
+func TestTiming(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/layout-shift",
+		WithWaitStrategy(WaitDOMStable),
+		WithSelector("#title", 5*time.Second),
+		WithStripScripts(),
+		WithMarkdown())
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	timing := result.Timing
+
+	for name, d := range map[string]time.Duration{
+		"Setup":            timing.Setup,
+		"Navigation":       timing.Navigation,
+		"Wait":             timing.Wait,
+		"SelectorWait":     timing.SelectorWait,
+		"Transform":        timing.Transform,
+		"Capture":          timing.Capture,
+		"Markdown":         timing.Markdown,
+		"TTFB":             timing.TTFB,
+		"DOMContentLoaded": timing.DOMContentLoaded,
+		"Load":             timing.Load,
+		"FCP":              timing.FCP,
+		"LCP":              timing.LCP,
+		"TaskDuration":     timing.TaskDuration,
+	} {
+		if d <= 0 {
+			t.Errorf("Timing.%sが記録されていません", name)
+		}
+	}
+
+	// WaitDOMStableは500ms以上安定するまで待つ
+	if timing.Wait < 500*time.Millisecond {
+		t.Errorf("Timing.Wait: got %v", timing.Wait)
+	}
+	if sum := timing.Setup + timing.Navigation + timing.Wait + timing.SelectorWait + timing.Transform + timing.Capture; sum > result.Duration {
+		t.Errorf("内訳の合計がDurationを超えています: sum=%v duration=%v", sum, result.Duration)
+	}
+	if timing.Load < timing.DOMContentLoaded {
+		t.Errorf("Load(%v)がDOMContentLoaded(%v)より前です", timing.Load, timing.DOMContentLoaded)
+	}
+	if timing.CLS <= 0 {
+		t.Errorf("レイアウトシフトが記録されていません: CLS=%v", timing.CLS)
+	}
+	if timing.JSHeapUsedSize <= 0 {
+		t.Errorf("JSHeapUsedSizeが記録されていません")
+	}
+	// ローカルのHTTPサーバーにはTLSがない
+	if timing.TLS != 0 {
+		t.Errorf("Timing.TLS: got %v, want 0", timing.TLS)
+	}
+}
+
+// TestRedirects はHTTP・meta refresh・JavaScript・Refreshヘッダーによるリダイレクトの履歴と
+// 最終ドキュメントのレスポンスヘッダーが記録されることを検証する
+func TestRedirects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/redirect-chain/start",
+		WithSelector("#final", 10*time.Second))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	if result.FinalURL != ts.URL+"/redirect-chain/final" {
+		t.Errorf("FinalURL: got %q", result.FinalURL)
+	}
+	if result.StatusCode != 200 {
+		t.Errorf("StatusCode: got %d, want 200", result.StatusCode)
+	}
+	if got := result.Headers.Get("X-Robots-Tag"); got != "noindex" {
+		t.Errorf("Headers X-Robots-Tag: got %q", got)
+	}
+	if got := result.Headers.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Headers Cache-Control: got %q", got)
+	}
+
+	want := []struct {
+		from, to, typ string
+		status        int
+	}{
+		{"start", "meta", RedirectHTTP, 301},
+		{"meta", "js", RedirectMetaRefresh, 200},
+		{"js", "refresh", RedirectJavaScript, 200},
+		{"refresh", "final", RedirectRefreshHeader, 200},
+	}
+	if len(result.Redirects) != len(want) {
+		t.Fatalf("Redirects: got %d件, want %d件: %+v", len(result.Redirects), len(want), result.Redirects)
+	}
+	for i, w := range want {
+		r := result.Redirects[i]
+		if r.URL != ts.URL+"/redirect-chain/"+w.from || r.Location != ts.URL+"/redirect-chain/"+w.to {
+			t.Errorf("Redirects[%d]: got %s -> %s", i, r.URL, r.Location)
+		}
+		if r.Type != w.typ {
+			t.Errorf("Redirects[%d].Type: got %q, want %q", i, r.Type, w.typ)
+		}
+		if r.Status != w.status {
+			t.Errorf("Redirects[%d].Status: got %d, want %d", i, r.Status, w.status)
+		}
+	}
+	if got := result.Redirects[0].Headers.Get("X-Hop"); got != "start" {
+		t.Errorf("Redirects[0] X-Hop: got %q", got)
+	}
+	if got := result.Redirects[0].Headers.Get("Location"); got != "/redirect-chain/meta" {
+		t.Errorf("Redirects[0] Location header: got %q", got)
+	}
+}
+
+// TestBlockRules はURL・ドメイン・リソースタイプを指定したブロックルールと、
+// ブロックより優先する許可ルールを検証する
+func TestBlockRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	u, _ := url.Parse(ts.URL)
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/",
+		WithBlocking(BlockingOptions{Stylesheet: true}),
+		WithBlockRules(
+			BlockRule{Name: "local-images", Domains: []string{u.Hostname()}, ResourceTypes: []string{"Image"}},
+			BlockRule{Allow: true, URLGlob: "*/assets/style.css"},
+		))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	stats := result.Stats
+
+	if !strings.Contains(result.HTML, "ASSETS_MARKER") {
+		t.Error("ページ本体が取得できていません")
+	}
+	if stats.BlockedByRule["local-images"] == 0 {
+		t.Errorf("ブロックルールでブロックされていません: %v", stats.BlockedByRule)
+	}
+	if stats.BlockedByRule["Stylesheet"] != 0 {
+		t.Errorf("許可ルールに一致したCSSがブロックされています: %v", stats.BlockedByRule)
+	}
+	if css := stats.ByResourceType["Stylesheet"]; css == nil || css.Count != 1 {
+		t.Errorf("Stylesheetの統計: %+v", css)
+	}
+
+	failures := make(map[string]RequestFailure)
+	for _, f := range stats.Failures {
+		failures[f.URL] = f
+	}
+	if f := failures[ts.URL+"/assets/pixel.png"]; f.Reason != FailureBlocked || f.BlockedBy != "local-images" {
+		t.Errorf("ブロックした画像: %+v", f)
+	}
+}
+
+// TestFilterLists はAdblock Plus形式のフィルタリストでリクエストがブロックされ、
+// 読み込めないフィルタリストはStart()でエラーになることを検証する
+func TestFilterLists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "filters.txt")
+	list := "! テスト用\n/assets/style.css$stylesheet\n/pixel.png$third-party\n##.ad\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := New(WithStealth(false), WithFilterLists(path))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/assets/")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	stats := result.Stats
+
+	if stats.BlockedByRule[BlockRuleAds] != 1 {
+		t.Errorf("BlockedByRule: %v", stats.BlockedByRule)
+	}
+	// ファーストパーティの画像は$third-partyのフィルタに一致しない
+	if img := stats.ByResourceType["Image"]; img == nil || img.Count == 0 || img.Blocked != 0 {
+		t.Errorf("Imageの統計: %+v", img)
+	}
+	failures := make(map[string]RequestFailure)
+	for _, f := range stats.Failures {
+		failures[f.URL] = f
+	}
+	if f := failures[ts.URL+"/assets/style.css"]; f.Reason != FailureBlocked || f.BlockedBy != BlockRuleAds {
+		t.Errorf("ブロックしたCSS: %+v", f)
+	}
+
+	t.Run("missing_file", func(t *testing.T) {
+		err := New(WithFilterLists(filepath.Join(t.TempDir(), "missing.txt"))).Start()
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) || fetchErr.Code != ErrFilterListLoadFailed {
+			t.Errorf("エラー: got %v", err)
+		}
+	})
+}
+
+// TestCosmeticFiltering は組み込みのセレクタ・指定したルール・フィルタリストの要素隠しフィルタに
+// 一致する要素がHTMLとMarkdownから取り除かれることを検証する
+func TestCosmeticFiltering(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "filters.txt")
+	u, _ := url.Parse(ts.URL)
+	list := u.Hostname() + "##.list-ad\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := New(WithStealth(false), WithFilterLists(path))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("enabled", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/cosmetic",
+			WithCosmeticFiltering(
+				CosmeticRule{Selector: "#newsletter-modal"},
+				CosmeticRule{Selector: "article h1", Domains: []string{"other.example"}},
+			),
+			WithMarkdown())
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+
+		for _, marker := range []string{"COOKIE_BANNER", "ADSENSE_SLOT", "LIST_AD", "NEWSLETTER_MODAL"} {
+			if strings.Contains(result.HTML, marker) || strings.Contains(result.Markdown, marker) {
+				t.Errorf("%sが削除されていません", marker)
+			}
+		}
+		if !strings.Contains(result.HTML, "ARTICLE_BODY") || !strings.Contains(result.HTML, "記事タイトル") {
+			t.Error("本文が削除されています")
+		}
+		if result.RemovedElements != 4 {
+			t.Errorf("RemovedElements: got %d, want 4", result.RemovedElements)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/cosmetic")
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if !strings.Contains(result.HTML, "COOKIE_BANNER") || result.RemovedElements != 0 {
+			t.Error("WithCosmeticFiltering未指定時に要素が削除されています")
+		}
+	})
+}
+
+// TestConsentHandling はCookie同意ダイアログをボタンのクリック・CMPのJS APIで閉じてから
+// セレクタ待機・HTML取得が行われることを検証する
+func TestConsentHandling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	tests := []struct {
+		action     ConsentAction
+		wantMethod string
+		wantText   string
+	}{
+		{ConsentAccept, "click", "CONSENT_accepted"},
+		{ConsentReject, "api", "CONSENT_rejected"}, // 拒否ボタンがないためAPIで拒否する
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			result, err := fetcher.Fetch(context.Background(), ts.URL+"/consent",
+				WithConsentHandling(tt.action),
+				WithSelector("#content.ready", time.Second))
+			if err != nil {
+				t.Fatalf("Fetchに失敗: %v", err)
+			}
+			c := result.Consent
+			if c == nil {
+				t.Fatal("Consentが記録されていません")
+			}
+			if c.CMP != "onetrust" || c.Method != tt.wantMethod || !c.Closed {
+				t.Errorf("Consent: %+v", c)
+			}
+			if !strings.Contains(result.HTML, tt.wantText) {
+				t.Errorf("HTMLに%sが含まれていません", tt.wantText)
+			}
+			// ダイアログはload後300msで表示されるため、その待ち時間は待機戦略ではなくConsentに入る
+			if tm := result.Timing; tm.Consent < 250*time.Millisecond || tm.Wait >= tm.Consent {
+				t.Errorf("Timing: Wait %v / Consent %v", tm.Wait, tm.Consent)
+			}
+		})
+	}
+
+	t.Run("no_cmp", func(t *testing.T) {
+		start := time.Now()
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/", WithConsentHandling(ConsentReject))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if result.Consent != nil {
+			t.Errorf("Consent: %+v", result.Consent)
+		}
+		// CMPのないページでは検出を待たない
+		if d := time.Since(start); d > consentDetectTimeout {
+			t.Errorf("CMPのないページで待機しています: %v", d)
+		}
+	})
+}
+
+// TestActions はWithActionsのクリック・入力・キー操作・待機・スクロールが順に実行され、
+// 失敗した操作がActionErrorとして報告されることを検証する。
+func TestActions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("steps", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/actions",
+			WithActions(
+				Click("#more"),
+				Type("#q", "foo"),
+				Press("Enter"),
+				WaitFor(".results"),
+				Scroll(""),
+				Sleep(50*time.Millisecond),
+			),
+			WithSelector("#items li:nth-child(2)", time.Second))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		// 入力前の値は置き換えられる
+		for _, want := range []string{"ITEM_2", "RESULTS_FOR_foo<", `data-scrolled="true"`} {
+			if !strings.Contains(result.HTML, want) {
+				t.Errorf("HTMLに%sが含まれていません", want)
+			}
+		}
+		if result.Timing.Actions <= 0 {
+			t.Errorf("Timing.Actions: got %v", result.Timing.Actions)
+		}
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := fetcher.Fetch(context.Background(), ts.URL+"/actions",
+			WithActions(
+				Click("#more"),
+				Action{Kind: ActionClick, Selector: "#missing", Timeout: 500 * time.Millisecond},
+			))
+		var fe *FetchError
+		if !errors.As(err, &fe) || fe.Code != ErrActionFailed {
+			t.Fatalf("ErrActionFailedになりません: %v", err)
+		}
+		var ae *ActionError
+		if !errors.As(err, &ae) {
+			t.Fatalf("ActionErrorが含まれていません: %v", err)
+		}
+		if ae.Index != 1 || ae.Action.Selector != "#missing" {
+			t.Errorf("ActionError: %+v", ae)
+		}
+	})
+
+	t.Run("press_canceled", func(t *testing.T) {
+		// キー操作もFetchのcontextに従い、キャンセル後は実行しない
+		page, err := fetcher.browser.Page(proto.TargetCreateTarget{URL: ts.URL + "/actions"})
+		if err != nil {
+			t.Fatalf("タブの作成に失敗: %v", err)
+		}
+		defer page.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := runAction(page.Context(ctx), Press("Enter")); !errors.Is(err, context.Canceled) {
+			t.Errorf("キャンセル後のpress: got %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("selector_not_found", func(t *testing.T) {
+		// WithSelectorで要素が見つからない場合もpanicせずにエラーを返す
+		_, err := fetcher.Fetch(context.Background(), ts.URL+"/actions",
+			WithSelector("#missing", 500*time.Millisecond))
+		var fe *FetchError
+		if !errors.As(err, &fe) || fe.Code != ErrSelectorNotFound {
+			t.Fatalf("ErrSelectorNotFoundになりません: %v", err)
+		}
+	})
+}
+
+// TestAutoScroll はWithAutoScrollが新しい内容が追加されなくなるまで（またはMaxScrollsまで）スクロールし、
+// 途中の要素のIntersectionObserverを発火させ、data-src・data-srcsetを昇格することを検証する。
+func TestAutoScroll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	t.Run("all", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/infinite",
+			WithAutoScroll(AutoScrollOptions{IdleTimeout: 500 * time.Millisecond}))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		for _, want := range []string{
+			"ITEM_12",
+			`data-seen="true"`,
+			` src="/assets/pixel.png"`, // data-srcではなくsrc
+			` srcset="/assets/pixel.png 2x"`,
+			`loading="eager"`,
+		} {
+			if !strings.Contains(result.HTML, want) {
+				t.Errorf("HTMLに%sが含まれていません", want)
+			}
+		}
+		if result.Timing.AutoScroll <= 0 {
+			t.Errorf("Timing.AutoScroll: got %v", result.Timing.AutoScroll)
+		}
+	})
+
+	t.Run("max_scrolls", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/infinite",
+			WithAutoScroll(AutoScrollOptions{MaxScrolls: 1, IdleTimeout: 500 * time.Millisecond}))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if !strings.Contains(result.HTML, "ITEM_6") {
+			t.Error("HTMLにITEM_6が含まれていません")
+		}
+		if strings.Contains(result.HTML, "ITEM_10") {
+			t.Error("MaxScrollsを超えてスクロールしています")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/infinite")
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		if strings.Contains(result.HTML, "ITEM_4") || strings.Contains(result.HTML, ` src="/assets/pixel.png"`) {
+			t.Error("WithAutoScrollなしでスクロールしています")
+		}
+	})
+}
+
+// TestStealth_BotDetection はstealth有効時にbot検出チェックをパスすることを検証する。
+// 各チェック項目の詳細はtestserver_test.goのbotDetectPageコメントを参照。
+func TestStealth_BotDetection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(true))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	result, err := fetcher.Fetch(context.Background(), ts.URL+"/bot-detect",
+		WithWaitStrategy(WaitDOMStable))
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+
+	// <pre id="results">...</pre> からJSONを抽出
+	start := strings.Index(result.HTML, `<pre id="results">`)
+	end := strings.Index(result.HTML, `</pre>`)
+	if start == -1 || end == -1 {
+		t.Fatalf("結果のHTMLからJSONを抽出できません (HTML長: %d)", len(result.HTML))
+	}
+	jsonStr := result.HTML[start+len(`<pre id="results">`):end]
+
+	var results map[string]string
+	if err := json.Unmarshal([]byte(jsonStr), &results); err != nil {
+		t.Fatalf("結果JSONのパースに失敗: %v\nJSON: %s", err, jsonStr)
+	}
+
+	// stealth対策済みのチェック項目（PASSが期待される）
+	mustPass := []struct {
+		key  string
+		desc string
+	}{
+		{"webdriver", "navigator.webdriver が false/undefined"},
+		{"chrome_exists", "window.chrome が存在する"},
+		{"chrome_app", "window.chrome.app が存在する"},
+		{"chrome_csi", "window.chrome.csi が関数として存在する"},
+		{"chrome_loadTimes", "window.chrome.loadTimes が関数として存在する"},
+		{"plugins", "navigator.plugins が空でない"},
+		{"languages", "navigator.languages が空でない"},
+		{"outer_dimensions", "window.outerWidth/Height が 0 でない"},
+		{"user_agent", "User-Agentに HeadlessChrome が含まれない"},
+	}
+
+	for _, tc := range mustPass {
+		t.Run(tc.key, func(t *testing.T) {
+			v, ok := results[tc.key]
+			if !ok {
+				t.Errorf("チェック項目 %q が結果に含まれていません", tc.key)
+				return
+			}
+			if v != "PASS" {
+				t.Errorf("%s: %s (got %s)", tc.key, tc.desc, v)
+			}
+		})
+	}
+
+	// SKIPを許容するチェック項目（環境依存）
+	maySkip := []struct {
+		key  string
+		desc string
+	}{
+		{"notification_permission", "Notification.permission が denied でない"},
+		{"webgl", "WebGL renderer に SwiftShader が含まれない"},
+		{"fn_toString", "Permissions.prototype.query.toString() が [native code] を返す"},
+	}
+
+	for _, tc := range maySkip {
+		t.Run(tc.key, func(t *testing.T) {
+			v, ok := results[tc.key]
+			if !ok {
+				t.Skipf("チェック項目 %q が結果に含まれていません", tc.key)
+				return
+			}
+			switch v {
+			case "PASS":
+				// OK
+			case "SKIP":
+				t.Skipf("%s: 環境依存でスキップ", tc.key)
+			default:
+				t.Errorf("%s: %s (got %s)", tc.key, tc.desc, v)
+			}
+		})
+	}
+
+	// 全結果をログに出力（デバッグ用）
+	t.Logf("bot検出結果: %s", jsonStr)
+}
+
+// TestIgnoreCertErrors は自己署名証明書のHTTPSサーバーに対する動作を検証する。
+func TestIgnoreCertErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTLSTestServer(t)
+	defer ts.Close()
+
+	t.Run("WithoutOption_Fails", func(t *testing.T) {
+		fetcher := New(WithStealth(false))
+		if err := fetcher.Start(); err != nil {
+			t.Fatalf("ブラウザの起動に失敗: %v", err)
+		}
+		defer fetcher.Close()
+
+		_, err := fetcher.Fetch(context.Background(), ts.URL+"/",
+			WithWaitStrategy(WaitLoad))
+		if err == nil {
+			t.Error("証明書エラーが発生するはずですが、成功しました")
+		}
+	})
+
+	t.Run("WithOption_Succeeds", func(t *testing.T) {
+		fetcher := New(WithStealth(false), WithIgnoreCertErrors(true))
+		if err := fetcher.Start(); err != nil {
+			t.Fatalf("ブラウザの起動に失敗: %v", err)
+		}
+		defer fetcher.Close()
+
+		result, err := fetcher.Fetch(context.Background(), ts.URL+"/",
+			WithWaitStrategy(WaitLoad))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		assertContains(t, result.HTML, "STATIC_CONTENT_MARKER")
+	})
+}
+
+// TestPagePool_ConcurrentFetch はWithMaxConcurrentPages指定時に
+// 同時に使われるタブ数が上限を超えず、並列Fetchがすべて成功することを検証する。
+// 同時タブ数は、応答を遅らせたサーバーで同時に処理中のリクエスト数から測る。
+func TestPagePool_ConcurrentFetch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts, counter := newConcurrencyServer(t, 300*time.Millisecond)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false), WithMaxConcurrentPages(2))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 同じURLへの同時リクエストはChromeのキャッシュで直列化されるため、URLを変える
+			result, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/?n=%d", ts.URL, i),
+				WithWaitStrategy(WaitLoad))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !strings.Contains(result.HTML, "STATIC_CONTENT_MARKER") {
+				errs <- fmt.Errorf("HTMLにマーカーが含まれていません (HTML長: %d)", len(result.HTML))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Fetchに失敗: %v", err)
+	}
+
+	if peak := counter.Peak(); peak > 2 {
+		t.Errorf("同時に使われたタブ数が上限を超えています: %d", peak)
+	} else if peak < 2 {
+		t.Errorf("Fetchが並列に実行されていません: 同時タブ数 %d", peak)
+	}
+	if n := len(fetcher.pages.idle); n > 2 {
+		t.Errorf("再利用待ちのタブ数が上限を超えています: %d", n)
+	}
+}
+
+// TestPagePool_QueuedFetchRespectsContext はタブの空き待ち中に
+// コンテキストが終了した場合にFetchがエラーを返すことを検証する。
+func TestPagePool_QueuedFetchRespectsContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false), WithMaxConcurrentPages(1))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	// 唯一のスロットを占有する
+	page, err := fetcher.pages.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("タブの取得に失敗: %v", err)
+	}
+	defer fetcher.pages.release(page)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = fetcher.Fetch(ctx, ts.URL+"/")
+	var fe *FetchError
+	if !errors.As(err, &fe) || fe.Code != ErrFetchTimeout {
+		t.Fatalf("ErrFetchTimeoutが返されるべき: %v", err)
+	}
+}
+
+// TestBrowserPool_RecycleAndCrashRecovery はBrowserPoolが一定回数で
+// プロセスを再起動し、異常終了したプロセスを自動で立ち上げ直すことを検証する。
+func TestBrowserPool_RecycleAndCrashRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	pool := NewBrowserPool(
+		WithPoolSize(1),
+		WithRecycleAfter(2),
+		WithHealthCheckInterval(200*time.Millisecond),
+		WithFetcherOptions(WithStealth(false)),
+	)
+	if err := pool.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer pool.Close()
+
+	fetch := func() {
+		t.Helper()
+		result, err := pool.Fetch(context.Background(), ts.URL+"/",
+			WithWaitStrategy(WaitLoad))
+		if err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+		assertContains(t, result.HTML, "STATIC_CONTENT_MARKER")
+	}
+
+	// 2回フェッチすると再起動される
+	fetch()
+	fetch()
+	fetch()
+	if n := pool.Restarts(); n < 1 {
+		t.Errorf("フェッチ回数による再起動が行われていません: %d", n)
+	}
+
+	// ブラウザプロセスを強制終了しても次のFetchは成功する
+	before := pool.Restarts()
+	pool.mu.Lock()
+	pool.members[0].fetcher.launcher.Kill()
+	pool.mu.Unlock()
+
+	fetch()
+	if n := pool.Restarts(); n <= before {
+		t.Errorf("異常終了したプロセスが再起動されていません: %d", n)
+	}
+}
+
+// TestIsolatedContext はWithIsolatedContext指定時にCookieが
+// 他のFetchと共有されないことを検証する。
+func TestIsolatedContext(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		isolated   bool
+		wantCookie bool
+	}{
+		{"Shared", nil, false, true},
+		{"Isolated", nil, true, false},
+		{"IsolatedWithPool", []Option{WithMaxConcurrentPages(2)}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := New(append([]Option{WithStealth(false)}, tt.opts...)...)
+			if err := fetcher.Start(); err != nil {
+				t.Fatalf("ブラウザの起動に失敗: %v", err)
+			}
+			defer fetcher.Close()
+
+			var fetchOpts []FetchOption
+			if tt.isolated {
+				fetchOpts = append(fetchOpts, WithIsolatedContext())
+			}
+
+			if _, err := fetcher.Fetch(context.Background(), ts.URL+"/set-cookie", fetchOpts...); err != nil {
+				t.Fatalf("Fetchに失敗: %v", err)
+			}
+			result, err := fetcher.Fetch(context.Background(), ts.URL+"/echo-cookie", fetchOpts...)
+			if err != nil {
+				t.Fatalf("Fetchに失敗: %v", err)
+			}
+
+			if got := strings.Contains(result.HTML, "COOKIE_MARKER"); got != tt.wantCookie {
+				t.Errorf("Cookieの共有: got %v, want %v", got, tt.wantCookie)
+			}
+		})
+	}
+}
+
+// TestSession_SaveAndLoad はセッションのCookieとlocalStorageが
+// ファイル経由で別のセッションに引き継がれることを検証する。
+func TestSession_SaveAndLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("統合テストをスキップ（-short指定）")
+	}
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	fetcher := New(WithStealth(false))
+	if err := fetcher.Start(); err != nil {
+		t.Fatalf("ブラウザの起動に失敗: %v", err)
+	}
+	defer fetcher.Close()
+
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	// ログイン相当の状態を作って保存
+	first, err := fetcher.NewSession("test")
+	if err != nil {
+		t.Fatalf("セッションの作成に失敗: %v", err)
+	}
+	for _, u := range []string{"/set-cookie", "/storage?set"} {
+		if _, err := first.Fetch(context.Background(), ts.URL+u); err != nil {
+			t.Fatalf("Fetchに失敗: %v", err)
+		}
+	}
+	if err := first.Save(path); err != nil {
+		t.Fatalf("セッションの保存に失敗: %v", err)
+	}
+	first.Close()
+
+	// 別のセッションに復元
+	second, err := fetcher.NewSession("test")
+	if err != nil {
+		t.Fatalf("セッションの作成に失敗: %v", err)
+	}
+	defer second.Close()
+	if err := second.Load(path); err != nil {
+		t.Fatalf("セッションの読み込みに失敗: %v", err)
+	}
+
+	result, err := second.Fetch(context.Background(), ts.URL+"/echo-cookie")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	assertContains(t, result.HTML, "COOKIE_MARKER")
+
+	result, err = second.Fetch(context.Background(), ts.URL+"/storage")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	assertContains(t, result.HTML, "STORED:STORAGE_MARKER")
+
+	// セッション外のFetchには状態が漏れない
+	result, err = fetcher.Fetch(context.Background(), ts.URL+"/echo-cookie")
+	if err != nil {
+		t.Fatalf("Fetchに失敗: %v", err)
+	}
+	if strings.Contains(result.HTML, "COOKIE_MARKER") {
+		t.Error("セッションのCookieがセッション外のFetchに漏れています")
+	}
+}
+
 func assertContains(t *testing.T, html, marker string) {
 	t.Helper()
 	if !strings.Contains(html, marker) {
@@ -1,13 +1,18 @@
 package htmlfetch
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // Fetcher設定（内部状態）
 type fetcherConfig struct {
-	browserPath       string
-	stealth           bool
-	proxy             string
-	ignoreCertErrors  bool
+	browserPath      string
+	stealth          bool
+	proxy            string
+	ignoreCertErrors bool
+	maxPages         int
+	filterLists      []string
 }
 
 // Option はFetcher作成時のオプション
@@ -41,6 +46,24 @@ func WithStealth(enabled bool) Option {
 	}
 }
 
+// WithFilterLists はAdblock Plus / uBlock Origin形式のフィルタリスト（EasyList等）のファイルを読み込み、
+// 一致するリクエストを全てのFetchでブロックする。組み込みの広告ドメインリスト（BlockingOptions.Ads）の代わりに使われる
+// 要素隠しフィルタ（##）はWithCosmeticFiltering指定時に適用される
+// ファイルはStart()または最初のFetch()で読み込まれ、読み込みに失敗した場合はErrFilterListLoadFailedを返す
+func WithFilterLists(paths ...string) Option {
+	return func(c *fetcherConfig) {
+		c.filterLists = append(c.filterLists, paths...)
+	}
+}
+
+// WithMaxConcurrentPages は高速モードで同時に開くタブ数の上限を指定
+// 上限を超えたFetchは空きが出るまで待機し、使用済みのタブはリセットして再利用する
+func WithMaxConcurrentPages(n int) Option {
+	return func(c *fetcherConfig) {
+		c.maxPages = n
+	}
+}
+
 // Fetch実行時設定（内部状態）
 type fetchConfig struct {
 	waitStrategy    WaitStrategy
@@ -49,9 +72,34 @@ type fetchConfig struct {
 	viewportWidth   int
 	viewportHeight  int
 	blocking        BlockingOptions
+	blockRules      []BlockRule
+	cosmetic        bool
+	consent         ConsentAction
+	actions         []Action
+	autoScroll      *AutoScrollOptions
+	cosmeticRules   []CosmeticRule
 	embedCSS        bool
 	stripScripts    bool
 	markdown        bool
+	isolated        bool
+	session         *Session
+	headers         map[string]string
+	userAgent       string
+	acceptLanguage  string
+	device          *Device
+	timezone        string
+	locale          string
+	geolocation     *Geolocation
+	screenshot      *ScreenshotOptions
+	pdf             *PDFOptions
+	mhtml           bool
+	inlineAssets    bool
+	inlineMaxAsset  int64
+	inlineMaxTotal  int64
+	warc            io.Writer
+	har             bool
+	captureFilters  []ResponseFilter
+	console         bool
 }
 
 // FetchOption はFetch実行時のオプション
@@ -80,6 +128,41 @@ func WithViewport(width, height int) FetchOption {
 	}
 }
 
+// WithDevice はデバイス（画面サイズ・解像度・タッチ・UA・platform）をエミュレート
+// 指定した場合はWithViewportより優先される
+func WithDevice(d Device) FetchOption {
+	return func(c *fetchConfig) {
+		c.device = &d
+	}
+}
+
+// WithTimezone はタイムゾーンをエミュレート（例: "Asia/Tokyo"）
+func WithTimezone(tz string) FetchOption {
+	return func(c *fetchConfig) {
+		c.timezone = tz
+	}
+}
+
+// WithLocale はロケールをエミュレート（例: "ja-JP"）
+// Intl APIや日付の書式に影響する。Accept-LanguageはWithAcceptLanguageで指定する
+func WithLocale(locale string) FetchOption {
+	return func(c *fetchConfig) {
+		c.locale = locale
+	}
+}
+
+// WithGeolocation は位置情報をエミュレートし、geolocationの権限を許可する
+// accuracyはメートル単位
+func WithGeolocation(latitude, longitude, accuracy float64) FetchOption {
+	return func(c *fetchConfig) {
+		c.geolocation = &Geolocation{
+			Latitude:  latitude,
+			Longitude: longitude,
+			Accuracy:  accuracy,
+		}
+	}
+}
+
 // WithBlocking はリソースブロッキングを指定
 func WithBlocking(opts BlockingOptions) FetchOption {
 	return func(c *fetchConfig) {
@@ -87,6 +170,53 @@ func WithBlocking(opts BlockingOptions) FetchOption {
 	}
 }
 
+// WithBlockRules はURL・ドメイン・リソースタイプで指定したルールでリクエストをブロックする
+// 複数回指定した場合はルールが追加される。Allowのルールに一致したリクエストは、
+// 他のルールやWithBlockingの設定に関わらずブロックしない
+func WithBlockRules(rules ...BlockRule) FetchOption {
+	return func(c *fetchConfig) {
+		c.blockRules = append(c.blockRules, rules...)
+	}
+}
+
+// WithCosmeticFiltering は広告枠・Cookieバナー等の要素をHTML取得前にDOMから削除する
+// 組み込みのセレクタ（主要なCookieバナーと国内の広告枠）、指定したルール、
+// WithFilterListsのフィルタリストの要素隠しフィルタ（##）が適用される。複数回指定した場合はルールが追加される
+func WithCosmeticFiltering(rules ...CosmeticRule) FetchOption {
+	return func(c *fetchConfig) {
+		c.cosmetic = true
+		c.cosmeticRules = append(c.cosmeticRules, rules...)
+	}
+}
+
+// WithConsentHandling はページ読み込み後に既知のCMP（OneTrust, Cookiebot, Quantcast, TrustArc等）の
+// Cookie同意ダイアログを検出し、同意または拒否してダイアログが閉じるのを待つ
+// 処理はセレクタ待機・HTML取得の前に行われ、結果はResult.Consentに入る
+func WithConsentHandling(action ConsentAction) FetchOption {
+	return func(c *fetchConfig) {
+		c.consent = action
+	}
+}
+
+// WithActions はページ読み込み後にクリック・入力・スクロール等の操作を順に実行する
+// 操作は待機戦略・Cookie同意ダイアログの処理の後、セレクタ待機の前に行われる
+// いずれかの操作に失敗した場合、FetchはErrActionFailedのFetchError（CauseはActionError）を返す
+func WithActions(actions ...Action) FetchOption {
+	return func(c *fetchConfig) {
+		c.actions = append(c.actions, actions...)
+	}
+}
+
+// WithAutoScroll はHTML取得前にページ末尾までのスクロールを繰り返し、無限スクロールの続きを読み込む
+// 新しい内容が追加されなくなるか上限に達するまでスクロールし、IntersectionObserverによる遅延読み込みを発火させる
+// 最後にdata-src・data-srcset等をsrc・srcsetに昇格する。スクロールはWithActionsの操作の後、セレクタ待機の前に行われる
+func WithAutoScroll(opts AutoScrollOptions) FetchOption {
+	return func(c *fetchConfig) {
+		o := opts
+		c.autoScroll = &o
+	}
+}
+
 // WithEmbedCSS は外部CSSの埋め込みを有効化
 func WithEmbedCSS() FetchOption {
 	return func(c *fetchConfig) {
@@ -94,6 +224,23 @@ func WithEmbedCSS() FetchOption {
 	}
 }
 
+// WithInlineAssets は画像・フォント・favicon・CSS・同一オリジンのiframeをdata URIとして埋め込み、
+// 相対URLを絶対URLに書き換える。オフラインでもそのまま表示できる単一のHTMLになる
+func WithInlineAssets() FetchOption {
+	return func(c *fetchConfig) {
+		c.inlineAssets = true
+	}
+}
+
+// WithInlineAssetLimits は埋め込むリソースの上限サイズ（バイト）を指定
+// 1リソースあたりの上限（デフォルト: 5MB）と合計の上限（デフォルト: 50MB）を超えるものは絶対URLのまま残す
+func WithInlineAssetLimits(maxAssetSize, maxTotalSize int64) FetchOption {
+	return func(c *fetchConfig) {
+		c.inlineMaxAsset = maxAssetSize
+		c.inlineMaxTotal = maxTotalSize
+	}
+}
+
 // WithStripScripts はスクリプト除去を有効化
 func WithStripScripts() FetchOption {
 	return func(c *fetchConfig) {
@@ -108,6 +255,97 @@ func WithMarkdown() FetchOption {
 	}
 }
 
+// WithScreenshot はスクリーンショットの撮影を有効化
+// 待機・変換処理の後、HTML取得の直前に撮影するため、Result.HTMLと同じDOMが写る
+func WithScreenshot(opts ScreenshotOptions) FetchOption {
+	return func(c *fetchConfig) {
+		c.screenshot = &opts
+	}
+}
+
+// WithPDF はページをPDFとして出力する
+// スクリーンショットと同じく、HTML取得の直前に印刷用メディアでレンダリングされる
+func WithPDF(opts PDFOptions) FetchOption {
+	return func(c *fetchConfig) {
+		c.pdf = &opts
+	}
+}
+
+// WithMHTML はレンダリング後のページを画像・フォント・iframeを含むMHTMLアーカイブとして保存する
+func WithMHTML() FetchOption {
+	return func(c *fetchConfig) {
+		c.mhtml = true
+	}
+}
+
+// WithWARC はページが行った全てのリクエスト・レスポンスと描画後のDOMを
+// WARC/1.1形式（レコードごとにgzip圧縮）でwに書き込む
+func WithWARC(w io.Writer) FetchOption {
+	return func(c *fetchConfig) {
+		c.warc = w
+	}
+}
+
+// WithHAR はページが行った全てのリクエスト（ブロック・失敗したものを含む）を
+// HAR 1.2形式でResult.HARに記録する
+func WithHAR() FetchOption {
+	return func(c *fetchConfig) {
+		c.har = true
+	}
+}
+
+// WithCaptureResponses は条件に一致するレスポンスのボディをResult.Responsesに記録する
+// XHR/FetchでJSON APIから読み込まれるデータをDOMを経由せずに取得できる
+// 複数回指定した場合は、いずれかの条件に一致するレスポンスが記録される
+func WithCaptureResponses(filter ResponseFilter) FetchOption {
+	return func(c *fetchConfig) {
+		c.captureFilters = append(c.captureFilters, filter)
+	}
+}
+
+// WithConsoleCapture はコンソール出力（console.log等）・未捕捉のJS例外・ブラウザのログ（ネットワークエラー等）を
+// Result.Consoleに記録する。ページが空になる原因がJSのエラーかを調べる際に使う
+// CDPのRuntimeドメインを有効化するため、bot検出で自動操作と判定されやすくなる
+func WithConsoleCapture() FetchOption {
+	return func(c *fetchConfig) {
+		c.console = true
+	}
+}
+
+// WithIsolatedContext は高速モードでFetchごとに専用のブラウザコンテキストを使う
+// Cookie・localStorage・キャッシュが他のFetchと共有されない
+func WithIsolatedContext() FetchOption {
+	return func(c *fetchConfig) {
+		c.isolated = true
+	}
+}
+
+// WithHeaders はリクエストに追加するHTTPヘッダーを指定
+func WithHeaders(headers map[string]string) FetchOption {
+	return func(c *fetchConfig) {
+		if c.headers == nil {
+			c.headers = make(map[string]string, len(headers))
+		}
+		for k, v := range headers {
+			c.headers[k] = v
+		}
+	}
+}
+
+// WithUserAgent はUser-Agentを指定（デフォルト: ブラウザのUAからHeadlessChrome表記を除去したもの）
+func WithUserAgent(ua string) FetchOption {
+	return func(c *fetchConfig) {
+		c.userAgent = ua
+	}
+}
+
+// WithAcceptLanguage はAccept-Languageを指定（デフォルト: ja）
+func WithAcceptLanguage(lang string) FetchOption {
+	return func(c *fetchConfig) {
+		c.acceptLanguage = lang
+	}
+}
+
 // デフォルト値を適用
 func applyDefaults(c *fetchConfig) {
 	if c.waitStrategy == "" {
@@ -119,7 +357,24 @@ func applyDefaults(c *fetchConfig) {
 	if c.viewportHeight == 0 {
 		c.viewportHeight = 1080
 	}
+	if c.acceptLanguage == "" {
+		c.acceptLanguage = "ja"
+	}
 	if c.selectorTimeout == 0 {
 		c.selectorTimeout = 30 * time.Second
 	}
+	if c.autoScroll != nil {
+		if c.autoScroll.MaxScrolls <= 0 {
+			c.autoScroll.MaxScrolls = defaultMaxScrolls
+		}
+		if c.autoScroll.IdleTimeout <= 0 {
+			c.autoScroll.IdleTimeout = defaultScrollIdleTimeout
+		}
+	}
+	if c.inlineMaxAsset == 0 {
+		c.inlineMaxAsset = defaultInlineMaxAssetSize
+	}
+	if c.inlineMaxTotal == 0 {
+		c.inlineMaxTotal = defaultInlineMaxTotalSize
+	}
 }
@@ -0,0 +1,36 @@
+package htmlfetch
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globPattern はURL・MIMEタイプの照合に使うワイルドカードパターン
+// "*" は任意の文字列（空文字列を含む）、"?" は任意の1文字に一致する
+type globPattern struct {
+	re *regexp.Regexp
+}
+
+// compileGlob はワイルドカードパターンを正規表現に変換する
+// パターン全体が対象文字列全体に一致する必要がある
+func compileGlob(pattern string) *globPattern {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return &globPattern{re: regexp.MustCompile(sb.String())}
+}
+
+// match は文字列がパターンに一致するかを判定
+func (g *globPattern) match(s string) bool {
+	return g.re.MatchString(s)
+}
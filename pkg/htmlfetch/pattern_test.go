@@ -0,0 +1,30 @@
+package htmlfetch
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"*", "https://example.com/", true},
+		{"*/api/*", "https://example.com/api/items?page=1", true},
+		{"*/api/*", "https://example.com/static/app.js", false},
+		{"https://example.com/*.json", "https://example.com/data/list.json", true},
+		{"https://example.com/*.json", "https://example.com/list.json?v=1", false},
+		{"https://example.com/item?", "https://example.com/item1", true},
+		{"https://example.com/item?", "https://example.com/item", false},
+		{"application/*json", "application/json", true},
+		{"application/*json", "application/ld+json", true},
+		{"application/*json", "text/json", false},
+		// 正規表現のメタ文字はそのまま一致する
+		{"https://example.com/a.b(c)", "https://example.com/a.b(c)", true},
+		{"https://example.com/a.b(c)", "https://example.com/aXb(c)", false},
+	}
+	for _, tt := range tests {
+		if got := compileGlob(tt.pattern).match(tt.input); got != tt.want {
+			t.Errorf("compileGlob(%q).match(%q) = %v, want %v", tt.pattern, tt.input, got, tt.want)
+		}
+	}
+}
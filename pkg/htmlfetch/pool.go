@@ -0,0 +1,138 @@
+package htmlfetch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// pagePool は高速モードでタブを再利用し、同時に開くタブ数を制限する
+type pagePool struct {
+	create  func() (*rod.Page, error)
+	slots   chan struct{}
+	closing chan struct{} // close()で閉じられ、空き待ちのacquireを終了させる
+	mu      sync.Mutex
+	idle    []*rod.Page
+	closed  bool
+}
+
+// newPagePool は最大size個のタブを持つpagePoolを作成
+func newPagePool(size int, create func() (*rod.Page, error)) *pagePool {
+	return &pagePool{
+		create:  create,
+		slots:   make(chan struct{}, size),
+		closing: make(chan struct{}),
+	}
+}
+
+// acquire は空きスロットを待ってタブを取得する
+// 待機中にctxが終了した場合・プールが閉じられた場合はエラーを返す
+func (p *pagePool) acquire(ctx context.Context) (*rod.Page, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		page := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return page, nil
+	}
+	p.mu.Unlock()
+
+	page, err := p.create()
+	if err != nil {
+		p.done()
+		return nil, err
+	}
+	return page, nil
+}
+
+// release はタブの状態をリセットしてプールに戻す
+// リセットに失敗したタブは閉じて破棄する
+func (p *pagePool) release(page *rod.Page) {
+	defer p.done()
+
+	if err := resetPage(page); err != nil {
+		_ = page.Close()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = page.Close()
+		return
+	}
+	p.idle = append(p.idle, page)
+}
+
+// wait はスロットが空くまで待機する
+func (p *pagePool) wait(ctx context.Context) error {
+	select {
+	case <-p.closing:
+		return errPoolClosed
+	default:
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-p.closing:
+		return errPoolClosed
+	case <-ctx.Done():
+		return &FetchError{
+			Code:    ErrFetchTimeout,
+			Message: "タブの空き待ちがタイムアウトしました",
+			Cause:   ctx.Err(),
+		}
+	}
+}
+
+// done はスロットを解放する
+func (p *pagePool) done() {
+	<-p.slots
+}
+
+// errPoolClosed はプールが閉じられた後にタブを取得しようとした場合のエラー
+var errPoolClosed = &FetchError{
+	Code:    ErrInternalError,
+	Message: "Fetcherが終了したためタブを取得できません",
+}
+
+// close は再利用待ちのタブを破棄し、空き待ちのacquireにエラーを返す
+// （タブ自体はブラウザ終了時に閉じられる）
+func (p *pagePool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.idle = nil
+	close(p.closing)
+}
+
+// resetPage は再利用のためにタブの状態を初期化する
+func resetPage(page *rod.Page) error {
+	p := page.Timeout(10 * time.Second)
+
+	// インターセプトが残っているとリクエストが停止するため先に解除する
+	_ = proto.FetchDisable{}.Call(p)
+	_ = proto.PageStopLoading{}.Call(p)
+	_ = proto.NetworkSetExtraHTTPHeaders{Headers: proto.NetworkHeaders{}}.Call(p)
+	_ = proto.EmulationSetTouchEmulationEnabled{Enabled: false}.Call(p)
+	_ = proto.EmulationSetTimezoneOverride{TimezoneID: ""}.Call(p)
+	_ = proto.EmulationSetLocaleOverride{}.Call(p)
+	_ = proto.EmulationClearGeolocationOverride{}.Call(p)
+	_ = proto.RuntimeDisable{}.Call(p)
+	_ = proto.LogDisable{}.Call(p)
+	_ = proto.PerformanceDisable{}.Call(p)
+	// geolocationの権限はコンテキスト全体に付与されるため、Fetch側（grantGeolocation）で取り消す
+
+	return p.Navigate("about:blank")
+}
@@ -0,0 +1,44 @@
+package htmlfetch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+// TestPagePool_CloseWakesWaiters はclose()で空き待ちのacquireがエラーを返すことを検証する。
+func TestPagePool_CloseWakesWaiters(t *testing.T) {
+	p := newPagePool(1, func() (*rod.Page, error) { return &rod.Page{}, nil })
+	if _, err := p.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := p.acquire(context.Background())
+		errc <- err
+	}()
+
+	// 空き待ちに入るまで待ってから閉じる
+	time.Sleep(50 * time.Millisecond)
+	p.close()
+
+	select {
+	case err := <-errc:
+		var fe *FetchError
+		if !errors.As(err, &fe) {
+			t.Errorf("FetchErrorが返されません: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close()後もacquireが待機し続けています")
+	}
+
+	// 閉じた後のacquireもすぐにエラーを返す
+	if _, err := p.acquire(context.Background()); err == nil {
+		t.Error("close()後のacquireがエラーになりません")
+	}
+	p.close() // 2回呼んでもpanicしない
+}
@@ -0,0 +1,104 @@
+package htmlfetch
+
+import (
+	"net/http"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// documentResponse はメインフレームが最後に受信したドキュメントのレスポンス
+type documentResponse struct {
+	url     string
+	status  int
+	headers http.Header
+}
+
+// isMainDocument はメインフレームのドキュメントのリクエストかを判定
+// sc.mainFrameが不明な場合はDocumentであればメインフレームとみなす
+func (sc *statsCollector) isMainDocument(resourceType proto.NetworkResourceType, frameID proto.PageFrameID) bool {
+	if resourceType != proto.NetworkResourceTypeDocument {
+		return false
+	}
+	return sc.mainFrame == "" || frameID == sc.mainFrame
+}
+
+// recordHTTPRedirect はメインフレームのHTTPリダイレクトを記録する
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) recordHTTPRedirect(e *proto.NetworkRequestWillBeSent) {
+	if e.RedirectResponse == nil || !sc.isMainDocument(e.Type, e.FrameID) {
+		return
+	}
+	res := e.RedirectResponse
+	headers := httpHeader(res.Headers)
+	sc.redirects = append(sc.redirects, Redirect{
+		URL:      res.URL,
+		Status:   res.Status,
+		Location: e.Request.URL,
+		Headers:  headers,
+		Type:     RedirectHTTP,
+	})
+}
+
+// recordDocument はメインフレームのドキュメントのレスポンスを記録する
+// クライアントサイドのリダイレクトがあった場合は遷移先のドキュメントで上書きされる
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) recordDocument(e *proto.NetworkResponseReceived) {
+	if e.Response == nil || !sc.isMainDocument(e.Type, e.FrameID) {
+		return
+	}
+	sc.statusCode = e.Response.Status
+	sc.document = &documentResponse{
+		url:     e.Response.URL,
+		status:  e.Response.Status,
+		headers: httpHeader(e.Response.Headers),
+	}
+}
+
+// recordClientRedirect はmeta refresh・Refreshヘッダー・JavaScriptによるメインフレームの遷移を記録する
+// リンクのクリックやフォーム送信による遷移はリダイレクトとして扱わない
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) recordClientRedirect(e *proto.PageFrameRequestedNavigation) {
+	if sc.mainFrame != "" && e.FrameID != sc.mainFrame {
+		return
+	}
+	var redirectType string
+	switch e.Reason {
+	case proto.PageClientNavigationReasonMetaTagRefresh:
+		redirectType = RedirectMetaRefresh
+	case proto.PageClientNavigationReasonHTTPHeaderRefresh:
+		redirectType = RedirectRefreshHeader
+	case proto.PageClientNavigationReasonScriptInitiated:
+		redirectType = RedirectJavaScript
+	default:
+		return
+	}
+
+	redirect := Redirect{Location: e.URL, Type: redirectType}
+	if doc := sc.document; doc != nil {
+		redirect.URL = doc.url
+		redirect.Status = doc.status
+		redirect.Headers = doc.headers
+	}
+	sc.redirects = append(sc.redirects, redirect)
+}
+
+// getRedirects はリダイレクトの履歴と最終ドキュメントのレスポンスヘッダーを返す
+func (sc *statsCollector) getRedirects() ([]Redirect, http.Header) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var headers http.Header
+	if sc.document != nil {
+		headers = sc.document.headers
+	}
+	return append([]Redirect(nil), sc.redirects...), headers
+}
+
+// httpHeader はCDPのヘッダーをhttp.Headerに変換する
+func httpHeader(headers proto.NetworkHeaders) http.Header {
+	h := make(http.Header, len(headers))
+	for _, l := range headerLines(headers) {
+		h.Add(l[0], l[1])
+	}
+	return h
+}
@@ -0,0 +1,122 @@
+package htmlfetch
+
+import (
+	"strings"
+
+	"github.com/go-rod/rod"
+)
+
+// defaultMaxCapturedBodySize は記録するレスポンスボディのデフォルトの上限
+const defaultMaxCapturedBodySize = 10 << 20
+
+// responseMatcher はResponseFilterのワイルドカードを事前に変換したもの
+type responseMatcher struct {
+	filter    ResponseFilter
+	urlGlob   *globPattern
+	mimeTypes []*globPattern
+}
+
+// newResponseMatcher はResponseFilterから照合用の構造体を作成
+func newResponseMatcher(filter ResponseFilter) *responseMatcher {
+	m := &responseMatcher{filter: filter}
+	if filter.URLGlob != "" {
+		m.urlGlob = compileGlob(filter.URLGlob)
+	}
+	for _, t := range filter.MIMETypes {
+		m.mimeTypes = append(m.mimeTypes, compileGlob(strings.ToLower(t)))
+	}
+	if m.filter.MaxBodySize <= 0 {
+		m.filter.MaxBodySize = defaultMaxCapturedBodySize
+	}
+	return m
+}
+
+// match はレスポンスが条件を全て満たすかを判定
+func (m *responseMatcher) match(ex *exchange) bool {
+	if m.urlGlob != nil && !m.urlGlob.match(ex.url) {
+		return false
+	}
+	if m.filter.URLRegexp != nil && !m.filter.URLRegexp.MatchString(ex.url) {
+		return false
+	}
+	if len(m.filter.ResourceTypes) > 0 {
+		found := false
+		for _, t := range m.filter.ResourceTypes {
+			if strings.EqualFold(t, ex.resourceType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(m.mimeTypes) > 0 {
+		mimeType := strings.ToLower(ex.response.MIMEType)
+		found := false
+		for _, g := range m.mimeTypes {
+			if g.match(mimeType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// captureResponses は条件に一致するレスポンスのボディをブラウザから取得する
+// リダイレクト・読み込みに失敗したレスポンスは記録しない
+func captureResponses(page *rod.Page, sc *statsCollector, filters []ResponseFilter) []CapturedResponse {
+	matchers := make([]*responseMatcher, len(filters))
+	for i, f := range filters {
+		matchers[i] = newResponseMatcher(f)
+	}
+
+	var responses []CapturedResponse
+	for _, ex := range sc.getExchanges() {
+		sc.mu.Lock()
+		ok := ex.finished && !ex.redirected && ex.response != nil
+		sc.mu.Unlock()
+		if !ok || !isFetchableURL(ex.url) {
+			continue
+		}
+
+		var matched *responseMatcher
+		for _, m := range matchers {
+			if m.match(ex) {
+				matched = m
+				break
+			}
+		}
+		if matched == nil {
+			continue
+		}
+
+		sc.loadResponseBody(page, ex)
+		if !ex.bodyLoaded {
+			continue
+		}
+
+		body := ex.body
+		truncated := false
+		if int64(len(body)) > matched.filter.MaxBodySize {
+			body = body[:matched.filter.MaxBodySize]
+			truncated = true
+		}
+
+		responses = append(responses, CapturedResponse{
+			URL:          ex.url,
+			Method:       ex.method,
+			Status:       ex.response.Status,
+			Headers:      httpHeader(ex.response.Headers),
+			MIMEType:     ex.response.MIMEType,
+			ResourceType: ex.resourceType,
+			Body:         body,
+			Truncated:    truncated,
+		})
+	}
+	return responses
+}
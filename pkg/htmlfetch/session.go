@@ -0,0 +1,319 @@
+package htmlfetch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Session はログイン状態などを複数のFetchで引き継ぐための名前付きセッション
+// 専用のブラウザコンテキストを持ち、CookieとlocalStorageをJSONファイルに保存・復元できる
+type Session struct {
+	name    string
+	fetcher *Fetcher
+	browser *rod.Browser
+	mu      sync.Mutex
+	storage map[string]map[string]string // origin → localStorageの内容
+	pending map[string]bool              // ページ読み込み時に復元が必要なorigin
+}
+
+// SessionState はセッションの保存形式
+type SessionState struct {
+	Name         string                       `json:"name"`
+	Cookies      []Cookie                     `json:"cookies"`
+	LocalStorage map[string]map[string]string `json:"local_storage,omitempty"`
+}
+
+// Cookie はセッションが保持するCookie
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires,omitempty"` // Unix時間（秒）。0はセッションCookie
+	HTTPOnly bool    `json:"http_only,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	SameSite string  `json:"same_site,omitempty"`
+}
+
+// NewSession は新しいセッションを作成
+// Start()で起動したブラウザ上に専用のブラウザコンテキストを作成する
+// 使用後は必ずClose()を呼ぶこと
+func (f *Fetcher) NewSession(name string) (*Session, error) {
+	f.mu.Lock()
+	started := f.started
+	browser := f.browser
+	f.mu.Unlock()
+
+	if !started {
+		return nil, &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションの作成にはStart()が必要です",
+		}
+	}
+
+	contextBrowser, err := browser.Incognito()
+	if err != nil {
+		return nil, &FetchError{
+			Code:    ErrInternalError,
+			Message: "ブラウザコンテキストの作成に失敗しました",
+			Cause:   err,
+		}
+	}
+
+	return &Session{
+		name:    name,
+		fetcher: f,
+		browser: contextBrowser,
+		storage: make(map[string]map[string]string),
+		pending: make(map[string]bool),
+	}, nil
+}
+
+// Name はセッション名を返す
+func (s *Session) Name() string {
+	return s.name
+}
+
+// Close はセッションのブラウザコンテキストを破棄する
+func (s *Session) Close() error {
+	return s.browser.Close()
+}
+
+// Fetch はセッションのCookieとlocalStorageを使ってURLからHTMLを取得
+func (s *Session) Fetch(ctx context.Context, url string, opts ...FetchOption) (*Result, error) {
+	opts = append(opts, func(c *fetchConfig) {
+		c.session = s
+	})
+	return s.fetcher.Fetch(ctx, url, opts...)
+}
+
+// Cookies はセッションのCookieを返す
+func (s *Session) Cookies() ([]Cookie, error) {
+	cookies, err := s.browser.GetCookies()
+	if err != nil {
+		return nil, &FetchError{
+			Code:    ErrInternalError,
+			Message: "Cookieの取得に失敗しました",
+			Cause:   err,
+		}
+	}
+
+	result := make([]Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		}
+		if !c.Session {
+			cookie.Expires = float64(c.Expires)
+		}
+		result = append(result, cookie)
+	}
+	return result, nil
+}
+
+// SetCookies はセッションにCookieを追加する
+func (s *Session) SetCookies(cookies []Cookie) error {
+	if len(cookies) == 0 {
+		return nil
+	}
+
+	params := make([]*proto.NetworkCookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		params = append(params, &proto.NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  proto.TimeSinceEpoch(c.Expires),
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: proto.NetworkCookieSameSite(c.SameSite),
+		})
+	}
+
+	if err := s.browser.SetCookies(params); err != nil {
+		return &FetchError{
+			Code:    ErrInternalError,
+			Message: "Cookieの設定に失敗しました",
+			Cause:   err,
+		}
+	}
+	return nil
+}
+
+// LocalStorage はこれまでのFetchで取得したlocalStorageのスナップショットをorigin別に返す
+func (s *Session) LocalStorage() map[string]map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]map[string]string, len(s.storage))
+	for origin, items := range s.storage {
+		snapshot[origin] = copyItems(items)
+	}
+	return snapshot
+}
+
+// SetLocalStorage はoriginのlocalStorageを設定する
+// 次にそのoriginのページを読み込んだとき、ページのスクリプトより先に書き込まれる
+func (s *Session) SetLocalStorage(origin string, items map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.storage[origin] = copyItems(items)
+	s.pending[origin] = true
+}
+
+// State は保存用にセッションの状態を返す
+func (s *Session) State() (*SessionState, error) {
+	cookies, err := s.Cookies()
+	if err != nil {
+		return nil, err
+	}
+	return &SessionState{
+		Name:         s.name,
+		Cookies:      cookies,
+		LocalStorage: s.LocalStorage(),
+	}, nil
+}
+
+// Restore は保存された状態をセッションに復元する
+func (s *Session) Restore(state *SessionState) error {
+	if err := s.SetCookies(state.Cookies); err != nil {
+		return err
+	}
+	for origin, items := range state.LocalStorage {
+		s.SetLocalStorage(origin, items)
+	}
+	return nil
+}
+
+// Save はセッションの状態をJSONファイルに保存する
+func (s *Session) Save(path string) error {
+	state, err := s.State()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションのエンコードに失敗しました",
+			Cause:   err,
+		}
+	}
+
+	// Cookieを含むため所有者のみ読み書き可能にする
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションファイルの保存に失敗しました",
+			Cause:   err,
+		}
+	}
+	return nil
+}
+
+// Load はJSONファイルからセッションの状態を復元する
+func (s *Session) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションファイルの読み込みに失敗しました",
+			Cause:   err,
+		}
+	}
+
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return &FetchError{
+			Code:    ErrInternalError,
+			Message: "セッションファイルのパースに失敗しました",
+			Cause:   err,
+		}
+	}
+	return s.Restore(&state)
+}
+
+// prepare はページ読み込み前に復元待ちのlocalStorageを書き込むスクリプトを登録する
+func (s *Session) prepare(page *rod.Page) error {
+	s.mu.Lock()
+	pending := make(map[string]map[string]string, len(s.pending))
+	for origin := range s.pending {
+		pending[origin] = copyItems(s.storage[origin])
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	_, err = page.EvalOnNewDocument(`(function(data) {
+		var items = data[location.origin];
+		if (!items) return;
+		try {
+			for (var key in items) localStorage.setItem(key, items[key]);
+		} catch (e) {}
+	})(` + string(data) + `)`)
+	return err
+}
+
+// capture は表示中のページのlocalStorageをセッションに取り込む
+func (s *Session) capture(page *rod.Page) error {
+	res, err := page.Eval(`() => {
+		const items = {};
+		try {
+			for (let i = 0; i < localStorage.length; i++) {
+				const key = localStorage.key(i);
+				items[key] = localStorage.getItem(key);
+			}
+		} catch (e) {}
+		return {origin: location.origin, items: items};
+	}`)
+	if err != nil {
+		return err
+	}
+
+	var snapshot struct {
+		Origin string            `json:"origin"`
+		Items  map[string]string `json:"items"`
+	}
+	if err := res.Value.Unmarshal(&snapshot); err != nil {
+		return err
+	}
+	// about:blankやdata:URLなどのopaque originは保存しない
+	if snapshot.Origin == "" || snapshot.Origin == "null" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storage[snapshot.Origin] = snapshot.Items
+	delete(s.pending, snapshot.Origin)
+	return nil
+}
+
+// copyItems はlocalStorageの内容をコピーする
+func copyItems(items map[string]string) map[string]string {
+	copied := make(map[string]string, len(items))
+	for k, v := range items {
+		copied[k] = v
+	}
+	return copied
+}
@@ -1,113 +1,347 @@
 package htmlfetch
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
 )
 
 // requestInfo はリクエスト情報を一時保存する構造体
 type requestInfo struct {
+	url          string
+	method       string
 	resourceType string
 	requestSize  int64
+	blockedBy    string // ブロックしたルール（ブロックしない場合は空）
+	cached       bool   // キャッシュ・Service Workerから返された（通信量に含めない）
 }
 
 // statsCollector はネットワーク統計を収集する
 type statsCollector struct {
 	stats      *NetworkStats
 	requests   map[proto.NetworkRequestID]*requestInfo
-	blockSet   blockingSet
+	blocker    *blocker
+	docURL     string // ブロックの判定に使うメインフレームのドキュメントのURL
 	statusCode int
 	mu         sync.Mutex
+
+	// メインフレームのリダイレクトと最後に受信したドキュメント
+	mainFrame proto.PageFrameID
+	redirects []Redirect
+	document  *documentResponse
+
+	// requestWillBeSentより先に届いた送信ヘッダー（ExtraInfoとの順序は保証されない）
+	pendingHeaders map[proto.NetworkRequestID]proto.NetworkHeaders
+
+	// WARC・HAR出力のためにリクエストごとの記録を残すか
+	recordExchanges bool
+	exchanges       []*exchange
+	pending         map[proto.NetworkRequestID]*exchange
 }
 
 // newStatsCollector は新しいstatsCollectorを作成
-func newStatsCollector(blockSet blockingSet) *statsCollector {
+func newStatsCollector(b *blocker) *statsCollector {
 	return &statsCollector{
 		stats: &NetworkStats{
 			ByResourceType: make(map[string]*ResourceStat),
+			BlockedByRule:  make(map[string]int),
 		},
-		requests: make(map[proto.NetworkRequestID]*requestInfo),
-		blockSet: blockSet,
+		requests:       make(map[proto.NetworkRequestID]*requestInfo),
+		pendingHeaders: make(map[proto.NetworkRequestID]proto.NetworkHeaders),
+		blocker:        b,
+		pending:        make(map[proto.NetworkRequestID]*exchange),
 	}
 }
 
 // setupNetworkStats はネットワーク統計収集を設定
 func (sc *statsCollector) setupNetworkStats(page *rod.Page) {
 	// Networkドメインを有効化
-	_ = proto.NetworkEnable{}.Call(page)
+	// レスポンスボディを後から取得する場合は、ブラウザが保持するバッファを拡張する
+	enable := proto.NetworkEnable{}
+	if sc.recordExchanges {
+		enable.MaxTotalBufferSize = gson.Int(200 << 20)
+		enable.MaxResourceBufferSize = gson.Int(50 << 20)
+	}
+	_ = enable.Call(page)
+	sc.mainFrame = page.FrameID
 
 	// リクエスト送信時とレスポンス完了時のイベントを監視
 	go page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
 		sc.mu.Lock()
 		defer sc.mu.Unlock()
 
+		if sc.recordExchanges {
+			sc.recordRequest(e)
+		}
+		sc.recordHTTPRedirect(e)
+		if sc.isMainDocument(e.Type, e.FrameID) {
+			sc.docURL = e.Request.URL
+		}
+
 		resourceType := string(e.Type)
 		if resourceType == "" {
 			resourceType = "Other"
 		}
 
-		// ブロックされたリソースはカウントしない
-		if sc.blockSet.shouldBlock(resourceType) {
+		// ブロックされたリソースは通信量に含めず、ルール別に数える
+		// setupFetchBlockingと同じ判定を使うため、Fetchドメインのイベントを待たずに判定できる
+		if rule := sc.blocker.rule(resourceType, e.Request.URL, sc.docURL); rule != "" {
+			sc.requests[e.RequestID] = &requestInfo{
+				url:          e.Request.URL,
+				resourceType: resourceType,
+				blockedBy:    rule,
+			}
+			if ex, ok := sc.pending[e.RequestID]; ok {
+				ex.blockedBy = rule
+			}
+			sc.stats.BlockedCount++
+			sc.stats.BlockedByRule[rule]++
+			sc.resourceStat(resourceType).Blocked++
 			return
 		}
 
-		// リクエストヘッダーのサイズを概算
-		requestSize := int64(len(e.Request.URL))
-		if e.Request.PostData != "" {
-			requestSize += int64(len(e.Request.PostData))
+		// リダイレクトレスポンスはloadingFinishedが発生しないため、ここで受信量に加算する
+		if e.RedirectResponse != nil {
+			bytesIn := int64(e.RedirectResponse.EncodedDataLength)
+			sc.stats.RedirectCount++
+			sc.stats.RedirectBytesIn += bytesIn
+			sc.stats.TotalBytesIn += bytesIn
+			sc.resourceStat(resourceType).BytesIn += bytesIn
 		}
-		// ヘッダーサイズの概算を追加
-		requestSize += 500
 
-		sc.requests[e.RequestID] = &requestInfo{
+		info := &requestInfo{
+			url:          e.Request.URL,
+			method:       e.Request.Method,
 			resourceType: resourceType,
-			requestSize:  requestSize,
 		}
+		sc.requests[e.RequestID] = info
 
-		sc.stats.TotalBytesOut += requestSize
 		sc.stats.RequestCount++
+		sc.resourceStat(resourceType).Count++
+
+		// 送信ヘッダーはrequestWillBeSentExtraInfoで加算する（キャッシュから返される場合は送信しない）
+		sc.addBytesOut(info, int64(len(e.Request.PostData)))
+		if headers, ok := sc.pendingHeaders[e.RequestID]; ok {
+			delete(sc.pendingHeaders, e.RequestID)
+			sc.addRequestHeaders(e.RequestID, info, headers)
+		}
+	}, func(e *proto.NetworkRequestWillBeSentExtraInfo) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		// 実際に送信されたヘッダー（Cookie等を含む）
+		if info, ok := sc.requests[e.RequestID]; ok {
+			sc.addRequestHeaders(e.RequestID, info, e.Headers)
+		} else {
+			sc.pendingHeaders[e.RequestID] = e.Headers
+		}
+	}, func(e *proto.NetworkResponseReceivedExtraInfo) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
 
-		if sc.stats.ByResourceType[resourceType] == nil {
-			sc.stats.ByResourceType[resourceType] = &ResourceStat{}
+		// 受信したヘッダーのサイズ（ヘッダー込みの受信量はloadingFinishedで加算される）
+		size := int64(len(e.HeadersText))
+		if size == 0 {
+			size = responseHeaderSize(e.StatusCode, e.Headers)
+		}
+		sc.stats.HeaderBytesIn += size
+		if ex, ok := sc.pending[e.RequestID]; ok {
+			ex.responseHeadersSize = size
+		}
+	}, func(e *proto.NetworkRequestServedFromCache) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		if info, ok := sc.requests[e.RequestID]; ok && !info.cached {
+			info.cached = true
+			sc.stats.CacheHits++
 		}
-		sc.stats.ByResourceType[resourceType].Count++
-		sc.stats.ByResourceType[resourceType].BytesOut += requestSize
 	}, func(e *proto.NetworkResponseReceived) {
 		sc.mu.Lock()
 		defer sc.mu.Unlock()
 
-		// 最初のDocumentレスポンスのステータスコードを記録
-		if sc.statusCode == 0 && e.Type == proto.NetworkResourceTypeDocument && e.Response != nil {
-			sc.statusCode = e.Response.Status
+		// メインフレームのドキュメントのステータスコード・ヘッダーを記録
+		sc.recordDocument(e)
+
+		if ex, ok := sc.pending[e.RequestID]; ok {
+			ex.response = e.Response
+		}
+
+		// キャッシュ・Service Workerから返されたレスポンスは通信が発生しない
+		if info, ok := sc.requests[e.RequestID]; ok && e.Response != nil && !info.cached {
+			switch {
+			case e.Response.FromServiceWorker:
+				info.cached = true
+				sc.stats.ServiceWorkerResponses++
+			case e.Response.FromDiskCache || e.Response.FromPrefetchCache:
+				info.cached = true
+				sc.stats.CacheHits++
+			}
 		}
 	}, func(e *proto.NetworkLoadingFinished) {
 		sc.mu.Lock()
 		defer sc.mu.Unlock()
 
-		// レスポンスサイズを加算
+		if ex, ok := sc.pending[e.RequestID]; ok {
+			ex.finished = true
+			ex.endTime = e.Timestamp
+			ex.encodedLength = e.EncodedDataLength
+		}
+
+		// レスポンスサイズ（ヘッダーを含む）を加算
+		// キャッシュから返された場合もボディサイズが通知されるため除外する
+		info, ok := sc.requests[e.RequestID]
+		if ok && info.cached {
+			return
+		}
 		bytesIn := int64(e.EncodedDataLength)
 		sc.stats.TotalBytesIn += bytesIn
 
-		if info, ok := sc.requests[e.RequestID]; ok {
+		if ok {
 			if sc.stats.ByResourceType[info.resourceType] != nil {
 				sc.stats.ByResourceType[info.resourceType].BytesIn += bytesIn
 			}
 		}
+	}, func(e *proto.NetworkLoadingFailed) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		if ex, ok := sc.pending[e.RequestID]; ok {
+			ex.errorText = e.ErrorText
+			ex.blockedReason = string(e.BlockedReason)
+			ex.canceled = e.Canceled
+			ex.endTime = e.Timestamp
+		}
+
+		failure := RequestFailure{
+			ResourceType: string(e.Type),
+			Reason:       classifyFailure(e.ErrorText, e.Canceled, string(e.BlockedReason), e.CorsErrorStatus != nil),
+			ErrorText:    e.ErrorText,
+		}
+		if info, ok := sc.requests[e.RequestID]; ok {
+			failure.URL = info.url
+			failure.ResourceType = info.resourceType
+			failure.BlockedBy = info.blockedBy
+		}
+		sc.stats.Failures = append(sc.stats.Failures, failure)
+	}, func(e *proto.PageFrameRequestedNavigation) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		sc.recordClientRedirect(e)
+	}, func(e *proto.NetworkDataReceived) {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		if ex, ok := sc.pending[e.RequestID]; ok {
+			ex.dataLength += int64(e.DataLength)
+		}
 	})()
 }
 
-// getStatusCode は最初のDocumentレスポンスのHTTPステータスコードを返す
+// getStatusCode はメインフレームの最後のドキュメントのHTTPステータスコードを返す
 func (sc *statsCollector) getStatusCode() int {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 	return sc.statusCode
 }
 
+// addRequestHeaders は送信したヘッダーのサイズを送信量に加算する
+// HTTP/1.1ではリクエスト行も加算する。HTTP/2では疑似ヘッダー（:method等）がリクエスト行の代わりになる
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) addRequestHeaders(id proto.NetworkRequestID, info *requestInfo, headers proto.NetworkHeaders) {
+	var size int64
+	http2 := false
+	for _, l := range headerLines(headers) {
+		size += int64(len(l[0]) + len(": ") + len(l[1]) + len("\r\n"))
+		if strings.HasPrefix(l[0], ":") {
+			http2 = true
+		}
+	}
+	if !http2 {
+		target := info.url
+		if u, err := url.Parse(info.url); err == nil {
+			target = u.RequestURI()
+		}
+		size += int64(len(info.method) + len(" ") + len(target) + len(" HTTP/1.1\r\n"))
+	}
+	size += int64(len("\r\n"))
+
+	sc.stats.HeaderBytesOut += size
+	sc.addBytesOut(info, size)
+	if ex, ok := sc.pending[id]; ok {
+		ex.requestHeadersSize = size
+	}
+}
+
+// addBytesOut は送信量を加算する
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) addBytesOut(info *requestInfo, n int64) {
+	info.requestSize += n
+	sc.stats.TotalBytesOut += n
+	sc.resourceStat(info.resourceType).BytesOut += n
+}
+
+// responseHeaderSize はヘッダーの生テキストがない場合（HTTP/2等）にステータス行とヘッダーのサイズを求める
+func responseHeaderSize(status int, headers proto.NetworkHeaders) int64 {
+	size := int64(len(fmt.Sprintf("HTTP/1.1 %d %s\r\n", status, http.StatusText(status))))
+	for _, l := range headerLines(headers) {
+		size += int64(len(l[0]) + len(": ") + len(l[1]) + len("\r\n"))
+	}
+	return size + int64(len("\r\n"))
+}
+
+// resourceStat はリソースタイプ別統計を返す（なければ作成する）
+// sc.muを保持した状態で呼ぶこと
+func (sc *statsCollector) resourceStat(resourceType string) *ResourceStat {
+	stat := sc.stats.ByResourceType[resourceType]
+	if stat == nil {
+		stat = &ResourceStat{}
+		sc.stats.ByResourceType[resourceType] = stat
+	}
+	return stat
+}
+
 // getStats は収集した統計を返す
 func (sc *statsCollector) getStats() NetworkStats {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
-	return *sc.stats
+
+	// Fetch後に届いたイベントで結果が変わらないよう、マップ・スライスも複製する
+	stats := *sc.stats
+	stats.ByResourceType = make(map[string]*ResourceStat, len(sc.stats.ByResourceType))
+	for k, v := range sc.stats.ByResourceType {
+		stat := *v
+		stats.ByResourceType[k] = &stat
+	}
+	stats.BlockedByRule = make(map[string]int, len(sc.stats.BlockedByRule))
+	for k, v := range sc.stats.BlockedByRule {
+		stats.BlockedByRule[k] = v
+	}
+	stats.Failures = append([]RequestFailure(nil), sc.stats.Failures...)
+	stats.EstimatedBytesSaved = estimateBytesSaved(stats.ByResourceType)
+	return stats
+}
+
+// estimateBytesSaved はブロックしたリクエストの受信量を推定する
+// 同じリソースタイプで受信したリクエストの平均サイズ、なければtypicalResourceSizeを使う
+func estimateBytesSaved(byType map[string]*ResourceStat) int64 {
+	var saved int64
+	for resourceType, stat := range byType {
+		if stat.Blocked == 0 {
+			continue
+		}
+		size := typicalResourceSize[resourceType]
+		if stat.Count > 0 && stat.BytesIn > 0 {
+			size = stat.BytesIn / int64(stat.Count)
+		}
+		saved += size * int64(stat.Blocked)
+	}
+	return saved
 }
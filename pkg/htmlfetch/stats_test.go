@@ -0,0 +1,72 @@
+package htmlfetch
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestStatsCollector_GetStatsCopy はgetStatsの結果が、その後に届いたイベントで変わらないことを検証する。
+func TestStatsCollector_GetStatsCopy(t *testing.T) {
+	sc := newStatsCollector(nil)
+	sc.resourceStat("Image").Blocked = 1
+	sc.stats.BlockedByRule["Image"] = 1
+	sc.stats.Failures = append(sc.stats.Failures, RequestFailure{URL: "https://example.com/a.png"})
+
+	stats := sc.getStats()
+
+	// Fetch後にイベントのgoroutineが統計を更新する
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		sc.resourceStat("Image").Blocked++
+		sc.resourceStat("Script").Count++
+		sc.stats.BlockedByRule["Image"]++
+		sc.stats.Failures = append(sc.stats.Failures, RequestFailure{URL: "https://example.com/b.png"})
+		sc.stats.Failures[0].Reason = FailureBlocked
+	}()
+	wg.Wait()
+
+	if got := stats.ByResourceType["Image"].Blocked; got != 1 {
+		t.Errorf("ByResourceType[Image].Blocked: got %d, want 1", got)
+	}
+	if _, ok := stats.ByResourceType["Script"]; ok {
+		t.Error("ByResourceTypeに後から追加されたリソースタイプが含まれています")
+	}
+	if got := stats.BlockedByRule["Image"]; got != 1 {
+		t.Errorf("BlockedByRule[Image]: got %d, want 1", got)
+	}
+	if len(stats.Failures) != 1 || stats.Failures[0].Reason != "" {
+		t.Errorf("Failures: %+v", stats.Failures)
+	}
+}
+
+// TestStatsCollector_SnapshotExchanges はsnapshotExchangesの結果が、その後に届いたイベントで変わらないことを検証する。
+func TestStatsCollector_SnapshotExchanges(t *testing.T) {
+	sc := newStatsCollector(nil)
+	sc.exchanges = append(sc.exchanges, &exchange{url: "https://example.com/"})
+
+	exchanges := sc.snapshotExchanges()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		sc.exchanges[0].finished = true
+		sc.exchanges[0].body = []byte("body")
+		sc.exchanges[0].bodyLoaded = true
+		sc.exchanges = append(sc.exchanges, &exchange{url: "https://example.com/a.png"})
+	}()
+	wg.Wait()
+
+	if len(exchanges) != 1 {
+		t.Fatalf("exchanges: got %d, want 1", len(exchanges))
+	}
+	if ex := exchanges[0]; ex.finished || ex.bodyLoaded || ex.body != nil {
+		t.Errorf("コピー後の更新が反映されています: %+v", ex)
+	}
+}
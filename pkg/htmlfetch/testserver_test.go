@@ -1,10 +1,12 @@
 package htmlfetch
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -24,9 +26,69 @@ func newTestServer(t *testing.T) *httptest.Server {
 	mux.HandleFunc("/lang-redirect/en", handleLangEn)
 	mux.HandleFunc("/echo-headers", handleEchoHeaders)
 	mux.HandleFunc("/bot-detect", handleBotDetect)
+	mux.HandleFunc("/set-cookie", handleSetCookie)
+	mux.HandleFunc("/echo-cookie", handleEchoCookie)
+	mux.HandleFunc("/storage", handleStorage)
+	mux.HandleFunc("/device-info", handleDeviceInfo)
+	mux.HandleFunc("/env-info", handleEnvInfo)
+	mux.HandleFunc("/tall", handleTall)
+	mux.HandleFunc("/assets/", handleAssets)
+	mux.HandleFunc("/console", handleConsole)
+	mux.HandleFunc("/failures", handleFailures)
+	mux.HandleFunc("/cacheable", handleCacheable)
+	mux.HandleFunc("/layout-shift", handleLayoutShift)
+	mux.HandleFunc("/redirect-chain/", handleRedirectChain)
+	mux.HandleFunc("/cosmetic", handleCosmetic)
+	mux.HandleFunc("/consent", handleConsentPage)
+	mux.HandleFunc("/consent/otSDKStub.js", handleConsentStub)
+	mux.HandleFunc("/actions", handleActions)
+	mux.HandleFunc("/infinite", handleInfinite)
 	return httptest.NewServer(mux)
 }
 
+// concurrencyCounter は処理中のリクエスト数の最大値を記録する
+type concurrencyCounter struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+// Peak は同時に処理していたリクエスト数の最大値を返す
+func (c *concurrencyCounter) Peak() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.peak
+}
+
+// newConcurrencyServer は応答をdelayだけ遅らせ、同時に処理中のページのリクエスト数を数えるサーバーを起動する。
+// タブごとにページを1回読み込むため、最大値は同時に使われていたタブ数になる
+func newConcurrencyServer(t *testing.T, delay time.Duration) (*httptest.Server, *concurrencyCounter) {
+	t.Helper()
+	c := &concurrencyCounter{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		c.mu.Lock()
+		c.inFlight++
+		if c.inFlight > c.peak {
+			c.peak = c.inFlight
+		}
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			c.inFlight--
+			c.mu.Unlock()
+		}()
+
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(staticPage))
+	}))
+	return ts, c
+}
+
 // handleStatic は静的ページを返す（ベースラインテスト用）
 func handleStatic(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -100,18 +162,434 @@ const enPage = `<!DOCTYPE html>
 <body><p>LANG_EN_MARKER</p></body>
 </html>`
 
-// handleEchoHeaders はリクエストのUser-AgentとAccept-LanguageをHTML内に埋め込んで返す。
+// handleEchoHeaders はリクエストのUser-Agent・Accept-Language・X-Test-HeaderをHTML内に埋め込んで返す。
 func handleEchoHeaders(w http.ResponseWriter, r *http.Request) {
 	ua := r.Header.Get("User-Agent")
 	lang := r.Header.Get("Accept-Language")
+	custom := r.Header.Get("X-Test-Header")
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(w, `<!DOCTYPE html>
 <html><body>
 <p id="ua">%s</p>
 <p id="lang">%s</p>
-</body></html>`, ua, lang)
+<p id="custom">%s</p>
+</body></html>`, ua, lang, custom)
+}
+
+// handleSetCookie はテスト用のCookieを設定するページを返す
+func handleSetCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: "COOKIE_MARKER", Path: "/"})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(staticPage))
+}
+
+// handleEchoCookie はリクエストのCookieヘッダーをHTML内に埋め込んで返す
+func handleEchoCookie(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><body>
+<p id="cookie">%s</p>
+</body></html>`, r.Header.Get("Cookie"))
+}
+
+// handleStorage はlocalStorageの値を表示するページを返す。
+// ?set を付けると表示後に値を書き込む。
+func handleStorage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(storagePage))
+}
+
+const storagePage = `<!DOCTYPE html>
+<html><body>
+<p id="stored"></p>
+<script>
+  document.getElementById('stored').textContent = 'STORED:' + (localStorage.getItem('token') || 'EMPTY');
+  if (location.search.indexOf('set') !== -1) {
+    localStorage.setItem('token', 'STORAGE_MARKER');
+  }
+</script>
+</body></html>`
+
+// handleDeviceInfo は画面サイズ・解像度・タッチ対応・UA・platformをJSON形式でHTMLに埋め込むページを返す
+func handleDeviceInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(deviceInfoPage))
+}
+
+const deviceInfoPage = `<!DOCTYPE html>
+<html><head><meta name="viewport" content="width=device-width, initial-scale=1"></head>
+<body>
+<pre id="results"></pre>
+<script>
+  document.getElementById('results').textContent = JSON.stringify({
+    width: window.innerWidth,
+    dpr: window.devicePixelRatio,
+    touch: navigator.maxTouchPoints,
+    ua: navigator.userAgent,
+    platform: navigator.platform
+  });
+</script>
+</body></html>`
+
+// handleEnvInfo はタイムゾーン・ロケール・位置情報をJSON形式でHTMLに埋め込むページを返す。
+// 位置情報の取得が終わると <p id="done"> を追加する。
+func handleEnvInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(envInfoPage))
+}
+
+const envInfoPage = `<!DOCTYPE html>
+<html><body>
+<pre id="results"></pre>
+<script>
+  var results = {
+    timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+    locale: new Intl.NumberFormat().resolvedOptions().locale
+  };
+  function finish() {
+    document.getElementById('results').textContent = JSON.stringify(results);
+    var done = document.createElement('p');
+    done.id = 'done';
+    document.body.appendChild(done);
+  }
+  navigator.permissions.query({name: 'geolocation'}).then(function(p) {
+    results.geo_permission = p.state;
+    navigator.geolocation.getCurrentPosition(function(pos) {
+      results.latitude = pos.coords.latitude;
+      results.longitude = pos.coords.longitude;
+      finish();
+    }, function(err) {
+      results.geo_error = err.message;
+      finish();
+    }, {timeout: 5000});
+  });
+</script>
+</body></html>`
+
+// handleTall はビューポートより縦に長く、固定サイズの要素を持つページを返す
+func handleTall(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(tallPage))
+}
+
+const tallPage = `<!DOCTYPE html>
+<html><head><style>
+  body { margin: 0; }
+  #spacer { height: 3000px; }
+  #box { width: 200px; height: 100px; background: #c00; }
+</style></head>
+<body>
+<div id="spacer"></div>
+<div id="box"></div>
+</body></html>`
+
+// handleConsole はコンソール出力・未捕捉の例外・読み込みに失敗するリソースを含むページを返す
+func handleConsole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(consolePage))
+}
+
+const consolePage = `<!DOCTYPE html>
+<html><head><title>Console</title></head>
+<body>
+<p>CONSOLE_PAGE</p>
+<script>
+console.log("CONSOLE_LOG_MARKER", 42, {a: 1});
+console.warn("CONSOLE_WARN_MARKER");
+</script>
+<script>
+throw new Error("UNCAUGHT_ERROR_MARKER");
+</script>
+<img src="/missing.png">
+</body></html>`
+
+// handleFailures は読み込みに失敗するリソース（名前解決できないホスト・接続できないポート）と
+// ブロック対象の画像を参照するページを返す
+func handleFailures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(failuresPage))
+}
+
+const failuresPage = `<!DOCTYPE html>
+<html><head><title>Failures</title>
+<script src="http://nonexistent.invalid/app.js"></script>
+<link rel="stylesheet" href="http://127.0.0.1:2/refused.css">
+</head>
+<body>
+<p>FAILURES_PAGE</p>
+<img src="/assets/pixel.png">
+<img src="/assets/pixel.png?2">
+</body></html>`
+
+// handleCacheable はキャッシュ可能な画像を読み込み、読み込み後に同じURLをfetchで再取得するページを返す
+// /cacheable?img で1時間キャッシュ可能な画像を返す
+func handleCacheable(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.URL.Query()["img"]; ok {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write(pixelPNG)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(cacheablePage))
+}
+
+const cacheablePage = `<!DOCTYPE html>
+<html><body>
+<p>CACHEABLE_PAGE</p>
+<img src="/cacheable?img">
+<script>
+window.addEventListener('load', () => setTimeout(refetch, 300));
+function refetch() {
+  fetch('/cacheable?img').then(() => {
+    const p = document.createElement('p');
+    p.id = 'refetched';
+    p.textContent = 'REFETCHED';
+    document.body.appendChild(p);
+  });
+}
+</script>
+</body></html>`
+
+// handleLayoutShift は読み込み後に上部へ要素を挿入し、レイアウトシフトを発生させるページを返す
+func handleLayoutShift(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(layoutShiftPage))
+}
+
+const layoutShiftPage = `<!DOCTYPE html>
+<html><head><title>Layout Shift</title></head>
+<body>
+<h1 id="title">LAYOUT_SHIFT_PAGE</h1>
+<p>Some paragraph text that will move down when the banner is inserted.</p>
+<script>
+window.addEventListener('load', () => {
+  setTimeout(() => {
+    const banner = document.createElement('div');
+    banner.style.height = '300px';
+    banner.textContent = 'BANNER';
+    document.body.insertBefore(banner, document.body.firstChild);
+  }, 50);
+});
+</script>
+</body></html>`
+
+// handleRedirectChain はHTTP・meta refresh・JavaScript・Refreshヘッダーによるリダイレクトを順に経由して
+// 最終ページに到達するページ群を返す
+func handleRedirectChain(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/redirect-chain/start":
+		w.Header().Set("X-Hop", "start")
+		http.Redirect(w, r, "/redirect-chain/meta", http.StatusMovedPermanently)
+	case "/redirect-chain/meta":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><head><meta http-equiv="refresh" content="0; url=/redirect-chain/js"></head><body>META</body></html>`))
+	case "/redirect-chain/js":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><body>JS<script>location.href = '/redirect-chain/refresh';</script></body></html>`))
+	case "/redirect-chain/refresh":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Refresh", "0; url=/redirect-chain/final")
+		w.Write([]byte(`<!DOCTYPE html><html><body>REFRESH</body></html>`))
+	case "/redirect-chain/final":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		w.Write([]byte(`<!DOCTYPE html><html><body><p id="final">REDIRECT_FINAL</p></body></html>`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleCosmetic は本文と一緒にCookieバナー・広告枠・ニュースレターのモーダルを含むページを返す
+func handleCosmetic(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(cosmeticPage))
+}
+
+const cosmeticPage = `<!DOCTYPE html>
+<html><head><title>Cosmetic</title></head>
+<body>
+<div id="onetrust-consent-sdk"><p>COOKIE_BANNER</p><button>同意する</button></div>
+<article>
+  <h1>記事タイトル</h1>
+  <p>ARTICLE_BODY</p>
+  <ins class="adsbygoogle"><span>ADSENSE_SLOT</span></ins>
+  <div class="list-ad">LIST_AD</div>
+</article>
+<div id="newsletter-modal">NEWSLETTER_MODAL</div>
+</body></html>`
+
+// handleConsentPage はOneTrust風のCookie同意ダイアログを表示し、同意・拒否するまで本文を表示しないページを返す
+func handleConsentPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(consentPage))
 }
 
+// handleConsentStub はOneTrustのローダーを模したスクリプトを返す。
+// 読み込みから300ms後にダイアログを表示し、拒否ボタンは表示せずJS API（OneTrust.RejectAll）でのみ拒否できる
+func handleConsentStub(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Write([]byte(consentStubJS))
+}
+
+const consentPage = `<!DOCTYPE html>
+<html><head><title>Consent</title>
+<script src="/consent/otSDKStub.js"></script>
+</head>
+<body>
+<div id="content"></div>
+</body></html>`
+
+const consentStubJS = `
+function decide(choice) {
+  document.cookie = 'consent=' + choice;
+  document.getElementById('onetrust-banner-sdk').style.display = 'none';
+  const content = document.getElementById('content');
+  content.textContent = 'CONSENT_' + choice;
+  content.className = 'ready';
+}
+window.OneTrust = {
+  AllowAll: () => decide('accepted'),
+  RejectAll: () => decide('rejected'),
+};
+window.addEventListener('load', () => {
+  setTimeout(() => {
+    const banner = document.createElement('div');
+    banner.id = 'onetrust-banner-sdk';
+    banner.innerHTML = '<p>Cookieを使用しています</p><button id="onetrust-accept-btn-handler">同意する</button>';
+    document.body.appendChild(banner);
+    document.getElementById('onetrust-accept-btn-handler').addEventListener('click', () => decide('accepted'));
+  }, 300);
+});
+`
+
+// handleActions は操作しないと表示されない内容を持つページを返す。
+// 「もっと見る」ボタンで200ms後に項目を追加し、検索フォームでEnterを押すと300ms後に結果を表示する
+func handleActions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(actionsPage))
+}
+
+const actionsPage = `<!DOCTYPE html>
+<html><head><title>Actions</title></head>
+<body>
+<ul id="items"><li>ITEM_1</li></ul>
+<button id="more">もっと見る</button>
+<form id="search"><input id="q" value="初期値"></form>
+<div id="footer" style="margin-top: 3000px">FOOTER</div>
+<script>
+document.getElementById('more').addEventListener('click', () => {
+  setTimeout(() => {
+    const li = document.createElement('li');
+    li.textContent = 'ITEM_2';
+    document.getElementById('items').appendChild(li);
+  }, 200);
+});
+document.getElementById('search').addEventListener('submit', (e) => {
+  e.preventDefault();
+  const q = document.getElementById('q').value;
+  setTimeout(() => {
+    const div = document.createElement('div');
+    div.className = 'results';
+    div.textContent = 'RESULTS_FOR_' + q;
+    document.body.appendChild(div);
+  }, 300);
+});
+window.addEventListener('scroll', () => { document.body.dataset.scrolled = 'true'; });
+</script>
+</body></html>`
+
+// handleInfinite は無限スクロールのページを返す。
+// 末尾の番兵要素が表示されると200ms後に3件ずつ項目を追加する（最大12件）。
+// 途中の#observedはIntersectionObserverで表示されるとdata-seen属性が付き、
+// 画像はdata-src・data-srcsetで遅延読み込みされる
+func handleInfinite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(infinitePage))
+}
+
+const infinitePage = `<!DOCTYPE html>
+<html><head><title>Infinite</title>
+<style>.item { height: 600px; } body { margin: 0; }</style>
+</head>
+<body>
+<div id="list"></div>
+<div id="sentinel">LOADING</div>
+<script>
+let count = 0;
+function addItems() {
+  const list = document.getElementById('list');
+  for (let i = 0; i < 3 && count < 12; i++) {
+    count++;
+    const div = document.createElement('div');
+    div.className = 'item';
+    div.textContent = 'ITEM_' + count;
+    if (count === 2) {
+      div.id = 'observed';
+      new IntersectionObserver((entries, o) => {
+        if (entries[0].isIntersecting) { div.dataset.seen = 'true'; o.disconnect(); }
+      }).observe(div);
+    }
+    if (count === 3) {
+      div.innerHTML += '<img id="lazy" src="data:image/gif;base64,R0lGODlhAQABAAAAACw=" data-src="/assets/pixel.png" data-srcset="/assets/pixel.png 2x" loading="lazy">';
+    }
+    list.appendChild(div);
+  }
+}
+addItems();
+new IntersectionObserver((entries) => {
+  if (entries[0].isIntersecting && count < 12) setTimeout(addItems, 200);
+}).observe(document.getElementById('sentinel'));
+</script>
+</body></html>`
+
+// handleAssets は画像・CSS・フォント・iframeを参照するページとそのサブリソースを返す
+func handleAssets(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/assets/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(assetsPage))
+	case "/assets/style.css":
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(assetsStyle))
+	case "/assets/pixel.png":
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pixelPNG)
+	case "/assets/font.woff2":
+		w.Header().Set("Content-Type", "font/woff2")
+		w.Write([]byte("FONT_MARKER"))
+	case "/assets/frame":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><body><p>FRAME_MARKER</p></body></html>`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// pixelPNG は1x1の透明PNG
+var pixelPNG, _ = base64.StdEncoding.DecodeString(
+	"iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==")
+
+const assetsPage = `<!DOCTYPE html>
+<html><head>
+<link rel="icon" href="pixel.png">
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<h1 class="title">ASSETS_MARKER</h1>
+<a id="home" href="../">home</a>
+<img id="img" src="pixel.png" srcset="pixel.png 1x, /assets/pixel.png?2x 2x">
+<div class="bg"></div>
+<iframe id="frame" src="frame"></iframe>
+</body></html>`
+
+const assetsStyle = `@font-face { font-family: "Test"; src: url("font.woff2") format("woff2"); }
+.title { font-family: "Test", sans-serif; }
+.bg { width: 10px; height: 10px; background: url(pixel.png); }
+`
+
 // handleBotDetect はブラウザのbot検出チェックを実行し、結果をJSON形式でHTMLに埋め込むページを返す。
 // 各チェックはbot検出サイト（bot.sannysoft.com, CreepJS等）で使われている手法を再現している。
 func handleBotDetect(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,97 @@
+package htmlfetch
+
+import (
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// pageTimingJS は最終ドキュメントのNavigation TimingとWeb Vitals（FCP・LCP・CLS）を取得する
+// LCP・レイアウトシフトはgetEntriesByTypeで取得できないため、buffered指定のPerformanceObserverで読み出す
+// 時間はいずれもナビゲーション開始からのミリ秒
+const pageTimingJS = `() => new Promise(resolve => {
+	const nav = performance.getEntriesByType('navigation')[0];
+	const fcp = performance.getEntriesByName('first-contentful-paint')[0];
+	const result = {
+		dns: nav ? nav.domainLookupEnd - nav.domainLookupStart : 0,
+		connect: nav ? nav.connectEnd - nav.connectStart : 0,
+		tls: nav && nav.secureConnectionStart > 0 ? nav.connectEnd - nav.secureConnectionStart : 0,
+		ttfb: nav && nav.responseStart > 0 ? nav.responseStart - nav.requestStart : 0,
+		domContentLoaded: nav ? nav.domContentLoadedEventEnd : 0,
+		load: nav ? nav.loadEventEnd : 0,
+		fcp: fcp ? fcp.startTime : 0,
+		lcp: 0,
+		cls: 0,
+	};
+	const observe = (type, fn) => {
+		try {
+			new PerformanceObserver(list => list.getEntries().forEach(fn)).observe({type, buffered: true});
+		} catch (e) {}
+	};
+	observe('largest-contentful-paint', e => { result.lcp = e.renderTime || e.loadTime || e.startTime; });
+	observe('layout-shift', e => { if (!e.hadRecentInput) result.cls += e.value; });
+	// バッファ済みのエントリは次のタスクで通知されるため、それを待ってから返す
+	setTimeout(() => resolve(result), 0);
+})`
+
+// loadPageTiming はNavigation Timing・Web Vitals・Performance.getMetricsの値をtimingに設定する
+// 取得できなかった値は0のまま残す
+func loadPageTiming(page *rod.Page, timing *Timing) {
+	if res, err := page.Eval(pageTimingJS); err == nil {
+		var v struct {
+			DNS              float64 `json:"dns"`
+			Connect          float64 `json:"connect"`
+			TLS              float64 `json:"tls"`
+			TTFB             float64 `json:"ttfb"`
+			DOMContentLoaded float64 `json:"domContentLoaded"`
+			Load             float64 `json:"load"`
+			FCP              float64 `json:"fcp"`
+			LCP              float64 `json:"lcp"`
+			CLS              float64 `json:"cls"`
+		}
+		if err := res.Value.Unmarshal(&v); err == nil {
+			timing.DNS = millis(v.DNS)
+			timing.Connect = millis(v.Connect)
+			timing.TLS = millis(v.TLS)
+			timing.TTFB = millis(v.TTFB)
+			timing.DOMContentLoaded = millis(v.DOMContentLoaded)
+			timing.Load = millis(v.Load)
+			timing.FCP = millis(v.FCP)
+			timing.LCP = millis(v.LCP)
+			timing.CLS = v.CLS
+		}
+	}
+
+	// Performance.getMetricsの時間は秒単位
+	if res, err := (proto.PerformanceGetMetrics{}).Call(page); err == nil {
+		for _, m := range res.Metrics {
+			switch m.Name {
+			case "ScriptDuration":
+				timing.ScriptDuration = seconds(m.Value)
+			case "LayoutDuration":
+				timing.LayoutDuration = seconds(m.Value)
+			case "TaskDuration":
+				timing.TaskDuration = seconds(m.Value)
+			case "JSHeapUsedSize":
+				timing.JSHeapUsedSize = int64(m.Value)
+			}
+		}
+	}
+}
+
+// millis はミリ秒をtime.Durationに変換する（負の値は0にする）
+func millis(ms float64) time.Duration {
+	if ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// seconds は秒をtime.Durationに変換する（負の値は0にする）
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -1,23 +1,106 @@
 package htmlfetch
 
-import "time"
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
 
 // Result はフェッチ結果
 type Result struct {
-	HTML        string
-	Markdown    string // WithMarkdown() 指定時のみ値が入る
-	FinalURL    string
-	StatusCode  int // 最終レスポンスのHTTPステータスコード
-	Stats       NetworkStats
-	Duration    time.Duration
+	HTML            string
+	Markdown        string             // WithMarkdown() 指定時のみ値が入る
+	Screenshot      []byte             // WithScreenshot() 指定時のみ値が入る
+	PDF             []byte             // WithPDF() 指定時のみ値が入る
+	MHTML           string             // WithMHTML() 指定時のみ値が入る
+	HAR             *HAR               // WithHAR() 指定時のみ値が入る
+	Responses       []CapturedResponse // WithCaptureResponses() 指定時のみ値が入る
+	Console         []ConsoleMessage   // WithConsoleCapture() 指定時のみ値が入る
+	RemovedElements int                // WithCosmeticFiltering() で削除した要素数
+	Consent         *ConsentResult     // WithConsentHandling() でCookie同意ダイアログを検出した場合のみ値が入る
+	FinalURL        string
+	StatusCode      int         // 最終レスポンスのHTTPステータスコード
+	Headers         http.Header // 最終ドキュメントのレスポンスヘッダー
+	Redirects       []Redirect  // FinalURLに至るまでのリダイレクト（発生順）
+	Stats           NetworkStats
+	Duration        time.Duration
+	Timing          Timing // Durationの内訳とページのパフォーマンス指標
 }
 
+// Timing はFetchの処理時間の内訳とページのパフォーマンス指標
+// ブラウザから取得できなかった値・実行しなかった処理は0になる
+type Timing struct {
+	// Fetchの各処理にかかった時間
+	Setup        time.Duration // タブの準備・エミュレーション等の設定
+	Navigation   time.Duration // ページへの遷移（ドキュメントのレスポンスを受信するまで）
+	Wait         time.Duration // 待機戦略による待機
+	Consent      time.Duration // WithConsentHandlingによるCookie同意ダイアログの処理
+	Actions      time.Duration // WithActionsの操作の実行
+	AutoScroll   time.Duration // WithAutoScrollによるスクロールと遅延読み込みの展開
+	SelectorWait time.Duration // WithSelectorによる要素の待機
+	Transform    time.Duration // CSS埋め込み・アセットのインライン化・スクリプト除去
+	Capture      time.Duration // スクリーンショット・PDF・MHTML・HTML取得・WARC・HAR等の出力
+	Markdown     time.Duration // Markdown変換（Durationには含まれない）
+
+	// 最終ドキュメントのNavigation Timing
+	DNS              time.Duration
+	Connect          time.Duration // TCP接続（TLSハンドシェイクを含む）
+	TLS              time.Duration
+	TTFB             time.Duration // リクエスト送信から最初のバイト受信まで
+	DOMContentLoaded time.Duration // ナビゲーション開始からDOMContentLoadedイベント完了まで
+	Load             time.Duration // ナビゲーション開始からloadイベント完了まで
+
+	// Web Vitals（ナビゲーション開始からの時間）
+	FCP time.Duration // First Contentful Paint
+	LCP time.Duration // Largest Contentful Paint（待機終了時点の値）
+	CLS float64       // Cumulative Layout Shift（待機終了時点までのレイアウトシフトの合計）
+
+	// Performance.getMetricsの値
+	ScriptDuration time.Duration // JavaScriptの実行時間
+	LayoutDuration time.Duration // レイアウトの計算時間
+	TaskDuration   time.Duration // メインスレッドのタスク全体の時間
+	JSHeapUsedSize int64         // 使用中のJSヒープ（バイト）
+}
+
+// Redirect はリダイレクトの1ステップ
+type Redirect struct {
+	URL      string      // リダイレクト元のURL
+	Status   int         // リダイレクト元のステータスコード（クライアントサイドの場合はページのステータス）
+	Location string      // リダイレクト先の絶対URL
+	Headers  http.Header // リダイレクト元のレスポンスヘッダー
+	Type     string      // リダイレクトの種類（Redirect*）
+}
+
+// リダイレクトの種類
+const (
+	RedirectHTTP          = "http"           // 3xxレスポンス
+	RedirectMetaRefresh   = "meta-refresh"   // <meta http-equiv="refresh">
+	RedirectRefreshHeader = "refresh-header" // Refreshレスポンスヘッダー
+	RedirectJavaScript    = "javascript"     // location.href等のスクリプトによる遷移
+)
+
 // NetworkStats はネットワーク通信統計
+// TotalBytesInはヘッダーを含む受信量、TotalBytesOutは実際に送信したヘッダーとボディの合計
+// キャッシュ・Service Workerから返されたレスポンスは通信量に含めない
+// ブロックしたリクエストはTotalBytesIn・TotalBytesOut・RequestCountに含めず、BlockedCountで数える
 type NetworkStats struct {
 	TotalBytesIn   int64
 	TotalBytesOut  int64
-	RequestCount   int
+	RequestCount   int // リダイレクトはリダイレクト先ごとに1件と数える
 	ByResourceType map[string]*ResourceStat
+
+	HeaderBytesIn          int64 // TotalBytesInのうちレスポンスヘッダー
+	HeaderBytesOut         int64 // TotalBytesOutのうちリクエストヘッダー（リクエスト行を含む）
+	CacheHits              int   // キャッシュ（メモリ・ディスク・プリフェッチ）から返されたレスポンス数
+	ServiceWorkerResponses int   // Service Workerから返されたレスポンス数
+	RedirectCount          int   // リダイレクトレスポンス数
+	RedirectBytesIn        int64 // TotalBytesInのうちリダイレクトレスポンス
+
+	BlockedCount        int              // ブロックしたリクエスト数
+	BlockedByRule       map[string]int   // ルール別のブロック数（キー: リソースタイプ名、広告ドメインはBlockRuleAds、BlockRuleはName）
+	EstimatedBytesSaved int64            // ブロックにより削減できた受信量の推定値
+	Failures            []RequestFailure // 読み込みに失敗・ブロックされたリクエスト（発生順）
 }
 
 // ResourceStat はリソースタイプ別統計
@@ -25,6 +108,16 @@ type ResourceStat struct {
 	Count    int
 	BytesIn  int64
 	BytesOut int64
+	Blocked  int // ブロックしたリクエスト数（Countには含まない）
+}
+
+// RequestFailure は読み込みに失敗したリクエスト
+type RequestFailure struct {
+	URL          string
+	ResourceType string
+	Reason       string // 失敗理由（Failure*）
+	BlockedBy    string // ブロッキング設定によるブロックの場合はルール（BlockedByRuleのキー）
+	ErrorText    string // Chromiumのエラー（例: "net::ERR_NAME_NOT_RESOLVED"）
 }
 
 // BlockingOptions はブロック設定
@@ -40,6 +133,193 @@ type BlockingOptions struct {
 	Fetch      bool
 }
 
+// BlockRule はURL・ドメイン・リソースタイプで指定するブロック（または許可）のルール
+// 指定した条件を全て満たすリクエストに適用される。未指定（ゼロ値）の条件は全てに一致する
+// Allowのルールに一致したリクエストは、他のルール・BlockingOptionsより優先してブロックしない
+type BlockRule struct {
+	Name          string         // NetworkStats.BlockedByRuleのキー。空の場合はBlockRuleCustom
+	URLGlob       string         // URLのワイルドカードパターン（例: "*/tracking/*"）。"*"は任意の文字列、"?"は任意の1文字
+	URLRegexp     *regexp.Regexp // URLの正規表現
+	Domains       []string       // ドメイン（サブドメインにも一致。例: "example.com" は "cdn.example.com" にも一致）
+	ResourceTypes []string       // リソースタイプ（例: "Image", "Script"）
+	Allow         bool           // trueの場合はブロックせず許可する（許可リスト）
+}
+
+// CosmeticRule はDOMから削除する要素のルール（要素隠しフィルタ）
+type CosmeticRule struct {
+	Selector string   // CSSセレクタ（例: ".ad-banner", "#newsletter-modal"）
+	Domains  []string // 適用するページのドメイン（サブドメインにも一致）。空の場合は全てのページ
+}
+
+// ConsentAction はCookie同意ダイアログ（CMP）に対する操作
+type ConsentAction string
+
+const (
+	ConsentAccept ConsentAction = "accept" // 全て同意する
+	ConsentReject ConsentAction = "reject" // 必須以外を拒否する
+)
+
+// ConsentResult はCookie同意ダイアログの処理結果
+type ConsentResult struct {
+	CMP    string // 検出したCMP（"onetrust", "cookiebot", "quantcast", "trustarc", "didomi", "usercentrics"）
+	Method string // "click"（ボタンをクリック）または "api"（CMPのJS APIを呼び出し）。操作できなかった場合は空
+	Closed bool   // ダイアログが閉じたことを確認できたか
+}
+
+// ActionKind はWithActionsで実行する操作の種類
+type ActionKind string
+
+const (
+	ActionClick   ActionKind = "click"    // 要素をクリックする
+	ActionType    ActionKind = "type"     // 要素の値を置き換えて文字列を入力する
+	ActionPress   ActionKind = "press"    // キーを押す
+	ActionWaitFor ActionKind = "wait_for" // 要素が表示されるまで待つ
+	ActionScroll  ActionKind = "scroll"   // 要素までスクロールする（セレクタが空の場合はページ末尾）
+	ActionSleep   ActionKind = "sleep"    // 指定時間待つ
+)
+
+// Action はWithActionsで実行する1つの操作
+// JSONでは{"type": "click", "selector": "#more"}のように表し、
+// duration・timeoutは"500ms"・"10s"のような文字列で指定する
+type Action struct {
+	Kind     ActionKind
+	Selector string        // 対象要素のCSSセレクタ（click・type・wait_for・scroll）
+	Text     string        // 入力する文字列（type）
+	Key      string        // 押すキー（press。"Enter"・"Tab"・"Escape"・"ArrowDown"・"a"等）
+	Duration time.Duration // 待つ時間（sleep）
+	Timeout  time.Duration // 要素を待つ時間（0の場合は10秒）
+}
+
+// ActionError はWithActionsの操作の失敗。FetchError（ErrActionFailed）のCauseに入る
+type ActionError struct {
+	Index  int // 失敗した操作の位置（0始まり）
+	Action Action
+	Err    error
+}
+
+func (e *ActionError) Error() string {
+	target := e.Action.Selector
+	if e.Action.Kind == ActionPress {
+		target = e.Action.Key
+	}
+	if target == "" {
+		return fmt.Sprintf("ステップ%d (%s): %v", e.Index+1, e.Action.Kind, e.Err)
+	}
+	return fmt.Sprintf("ステップ%d (%s %q): %v", e.Index+1, e.Action.Kind, target, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// AutoScrollOptions はWithAutoScrollの設定
+type AutoScrollOptions struct {
+	MaxScrolls  int           // ページ末尾までスクロールする最大回数（0の場合は20）
+	MaxHeight   int           // スクロールするページの高さの上限（px、0の場合は無制限）
+	IdleTimeout time.Duration // スクロール後に新しい内容を待つ時間（0の場合は1秒）
+}
+
+// ScreenshotFormat はスクリーンショットの画像形式
+type ScreenshotFormat string
+
+const (
+	ScreenshotPNG  ScreenshotFormat = "png"
+	ScreenshotJPEG ScreenshotFormat = "jpeg"
+	ScreenshotWebP ScreenshotFormat = "webp"
+)
+
+// ScreenshotOptions はスクリーンショット設定
+type ScreenshotOptions struct {
+	Format   ScreenshotFormat // デフォルト: PNG
+	Quality  int              // JPEG/WebPの品質 (0-100)
+	FullPage bool             // ページ全体を撮影
+	Selector string           // 指定した要素の領域のみを撮影（FullPageより優先）
+}
+
+// PaperSize はPDFの用紙サイズ（インチ）
+type PaperSize struct {
+	Width  float64
+	Height float64
+}
+
+// 組み込みの用紙サイズ
+var (
+	PaperA4     = PaperSize{Width: 8.27, Height: 11.69}
+	PaperA3     = PaperSize{Width: 11.69, Height: 16.54}
+	PaperLetter = PaperSize{Width: 8.5, Height: 11}
+	PaperLegal  = PaperSize{Width: 8.5, Height: 14}
+)
+
+// PDFMargin はPDFの余白（インチ）
+type PDFMargin struct {
+	Top    float64
+	Bottom float64
+	Left   float64
+	Right  float64
+}
+
+// PDFOptions はPDF出力設定
+type PDFOptions struct {
+	Paper           PaperSize  // デフォルト: A4
+	Margin          *PDFMargin // nilの場合はChromiumのデフォルト（約1cm）
+	Landscape       bool       // 横向き
+	PrintBackground bool       // 背景色・背景画像を印刷
+	Scale           float64    // 拡大率 (0.1-2)。0の場合は1
+	PageRanges      string     // 出力するページ範囲（例: "1-5, 8"）。空の場合は全ページ
+	// ヘッダー・フッターのHTMLテンプレート。いずれかを指定するとヘッダー・フッターが表示される
+	// date, title, url, pageNumber, totalPages クラスを持つ要素に値が挿入される
+	HeaderTemplate string
+	FooterTemplate string
+}
+
+// ResponseFilter はボディを記録するレスポンスの条件
+// 指定した条件を全て満たすレスポンスが記録される。未指定（ゼロ値）の条件は全てに一致する
+type ResponseFilter struct {
+	URLGlob       string         // URLのワイルドカードパターン（例: "*/api/*"）。"*"は任意の文字列、"?"は任意の1文字
+	URLRegexp     *regexp.Regexp // URLの正規表現
+	ResourceTypes []string       // リソースタイプ（例: "XHR", "Fetch", "Document"）
+	MIMETypes     []string       // MIMEタイプのワイルドカードパターン（例: "application/json", "*/*json"）
+	MaxBodySize   int64          // 1レスポンスあたりのボディの上限（バイト）。0の場合は10MB
+}
+
+// CapturedResponse は記録したレスポンス
+type CapturedResponse struct {
+	URL          string
+	Method       string
+	Status       int
+	Headers      http.Header
+	MIMEType     string
+	ResourceType string
+	Body         []byte
+	Truncated    bool // ボディがMaxBodySizeを超えたため切り詰められた
+}
+
+// ConsoleMessage はブラウザのコンソール出力・未捕捉の例外・ブラウザが出力したログ
+type ConsoleMessage struct {
+	Level     string // ログレベル（ConsoleLevel*）
+	Source    string // 発生元（ConsoleSource*、またはブラウザのログの種類: "network", "violation", "intervention" 等）
+	Text      string
+	URL       string // 出力したスクリプトのURL（不明な場合は空）
+	Line      int    // 行番号（1始まり、不明な場合は0）
+	Column    int    // 列番号（1始まり、不明な場合は0）
+	Timestamp time.Time
+}
+
+// コンソールのログレベル
+const (
+	ConsoleLevelDebug   = "debug"
+	ConsoleLevelLog     = "log"
+	ConsoleLevelInfo    = "info"
+	ConsoleLevelWarning = "warning"
+	ConsoleLevelError   = "error"
+)
+
+// コンソールメッセージの発生元
+const (
+	ConsoleSourceConsoleAPI = "console-api" // console.log等の呼び出し
+	ConsoleSourceException  = "exception"   // 未捕捉の例外・Promiseのreject
+)
+
 // WaitStrategy は待機戦略
 type WaitStrategy string
 
@@ -70,9 +350,12 @@ func (e *FetchError) Unwrap() error {
 
 // エラーコード定数
 const (
-	ErrBrowserLaunchFailed = "BROWSER_LAUNCH_FAILED"
-	ErrNavigationFailed    = "NAVIGATION_FAILED"
-	ErrFetchTimeout        = "FETCH_TIMEOUT"
-	ErrSelectorNotFound    = "SELECTOR_NOT_FOUND"
-	ErrInternalError       = "INTERNAL_ERROR"
+	ErrBrowserLaunchFailed  = "BROWSER_LAUNCH_FAILED"
+	ErrNavigationFailed     = "NAVIGATION_FAILED"
+	ErrFetchTimeout         = "FETCH_TIMEOUT"
+	ErrSelectorNotFound     = "SELECTOR_NOT_FOUND"
+	ErrCaptureFailed        = "CAPTURE_FAILED"
+	ErrFilterListLoadFailed = "FILTER_LIST_LOAD_FAILED"
+	ErrActionFailed         = "ACTION_FAILED"
+	ErrInternalError        = "INTERNAL_ERROR"
 )
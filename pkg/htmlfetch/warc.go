@@ -0,0 +1,201 @@
+package htmlfetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// warcWriter はWARC/1.1のレコードを1レコードずつgzip圧縮して書き込む
+// レコード単位で圧縮しておくと、アーカイブ側でオフセットを使ったランダムアクセスができる
+type warcWriter struct {
+	w io.Writer
+}
+
+// warcHeader はWARCレコードのヘッダー行
+type warcHeader struct {
+	name  string
+	value string
+}
+
+// writeWARC はページが行ったリクエスト・レスポンスと描画後のDOMをWARC形式で書き込む
+func writeWARC(w io.Writer, exchanges []*exchange, finalURL, html string, now time.Time) error {
+	ww := &warcWriter{w: w}
+
+	info := "software: nz-html-fetch\r\nformat: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+	err := ww.writeRecord([]warcHeader{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", newWARCRecordID()},
+		{"WARC-Date", formatWARCDate(now)},
+		{"Content-Type", "application/warc-fields"},
+	}, []byte(info))
+	if err != nil {
+		return err
+	}
+
+	for _, ex := range exchanges {
+		if ex.response == nil || !isFetchableURL(ex.url) || (!ex.redirected && !ex.bodyLoaded) {
+			continue
+		}
+
+		date := formatWARCDate(ex.wallTime)
+		responseID := newWARCRecordID()
+		requestID := newWARCRecordID()
+
+		response, payload := formatHTTPResponse(ex)
+		headers := []warcHeader{
+			{"WARC-Type", "response"},
+			{"WARC-Record-ID", responseID},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", ex.url},
+		}
+		if ex.response.RemoteIPAddress != "" {
+			headers = append(headers, warcHeader{"WARC-IP-Address", ex.response.RemoteIPAddress})
+		}
+		headers = append(headers,
+			warcHeader{"WARC-Block-Digest", warcDigest(response)},
+			warcHeader{"WARC-Payload-Digest", warcDigest(payload)},
+			warcHeader{"Content-Type", "application/http;msgtype=response"},
+		)
+		if err := ww.writeRecord(headers, response); err != nil {
+			return err
+		}
+
+		request := formatHTTPRequest(ex)
+		err := ww.writeRecord([]warcHeader{
+			{"WARC-Type", "request"},
+			{"WARC-Record-ID", requestID},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", ex.url},
+			{"WARC-Concurrent-To", responseID},
+			{"WARC-Block-Digest", warcDigest(request)},
+			{"Content-Type", "application/http;msgtype=request"},
+		}, request)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 描画後のDOMはレスポンスと区別するため urn:rendered-dom: を付けたURIで保存する
+	dom := []byte(html)
+	return ww.writeRecord([]warcHeader{
+		{"WARC-Type", "resource"},
+		{"WARC-Record-ID", newWARCRecordID()},
+		{"WARC-Date", formatWARCDate(now)},
+		{"WARC-Target-URI", "urn:rendered-dom:" + finalURL},
+		{"WARC-Block-Digest", warcDigest(dom)},
+		{"Content-Type", "text/html; charset=utf-8"},
+	}, dom)
+}
+
+// writeRecord は1つのレコードをgzipのメンバーとして書き込む
+func (ww *warcWriter) writeRecord(headers []warcHeader, block []byte) error {
+	gz := gzip.NewWriter(ww.w)
+
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.1\r\n")
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h.name, h.value)
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// formatHTTPRequest はリクエストをHTTP/1.1形式のメッセージにする
+func formatHTTPRequest(ex *exchange) []byte {
+	target := ex.url
+	host := ""
+	if u, err := url.Parse(ex.url); err == nil {
+		target = u.RequestURI()
+		host = u.Host
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", ex.method, target)
+	lines := sortedHeaderLines(ex.requestHeaders)
+	hasHost := false
+	for _, l := range lines {
+		if strings.EqualFold(l[0], "Host") {
+			hasHost = true
+		}
+	}
+	if !hasHost && host != "" {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+	for _, l := range lines {
+		fmt.Fprintf(&buf, "%s: %s\r\n", l[0], l[1])
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(ex.postData)
+	return buf.Bytes()
+}
+
+// formatHTTPResponse はレスポンスをHTTP/1.1形式のメッセージにし、メッセージとボディを返す
+// ブラウザから取得したボディは展開済みのため、Content-Encoding等は除いてContent-Lengthを付け直す
+func formatHTTPResponse(ex *exchange) ([]byte, []byte) {
+	res := ex.response
+	statusText := res.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(res.Status)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", res.Status, statusText)
+	for _, l := range sortedHeaderLines(res.Headers) {
+		switch strings.ToLower(l[0]) {
+		case "content-encoding", "transfer-encoding", "content-length":
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", l[0], l[1])
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(ex.body))
+	buf.Write(ex.body)
+	return buf.Bytes(), ex.body
+}
+
+// sortedHeaderLines はヘッダーを名前順に並べて返す
+func sortedHeaderLines(headers proto.NetworkHeaders) [][2]string {
+	lines := headerLines(headers)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return strings.ToLower(lines[i][0]) < strings.ToLower(lines[j][0])
+	})
+	return lines
+}
+
+// warcDigest はWARCで使われる形式（sha1:BASE32）のダイジェストを返す
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newWARCRecordID はUUID v4のレコードIDを作成する
+func newWARCRecordID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// formatWARCDate はWARC-Dateの形式（UTC、秒単位）に変換する
+func formatWARCDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
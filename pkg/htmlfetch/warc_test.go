@@ -0,0 +1,157 @@
+package htmlfetch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+	"github.com/ysmood/gson"
+)
+
+// warcRecord はテスト用に読み戻したWARCレコード
+type warcRecord struct {
+	headers textproto.MIMEHeader
+	block   []byte
+}
+
+// readWARCRecords はgzipメンバーごとに1レコードずつ読み戻す
+func readWARCRecords(t *testing.T, data []byte) []warcRecord {
+	t.Helper()
+
+	var records []warcRecord
+	br := bufio.NewReader(bytes.NewReader(data))
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			return records
+		}
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			t.Fatalf("gzipメンバーの読み込みに失敗: %v", err)
+		}
+		gz.Multistream(false)
+		member, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("gzipの展開に失敗: %v", err)
+		}
+
+		tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(member)))
+		version, err := tp.ReadLine()
+		if err != nil || version != "WARC/1.1" {
+			t.Fatalf("WARCバージョン行が不正: %q (%v)", version, err)
+		}
+		headers, err := tp.ReadMIMEHeader()
+		if err != nil {
+			t.Fatalf("WARCヘッダーの読み込みに失敗: %v", err)
+		}
+		length, err := strconv.Atoi(headers.Get("Content-Length"))
+		if err != nil {
+			t.Fatalf("Content-Lengthが不正: %v", err)
+		}
+		rest, _ := io.ReadAll(tp.R)
+		if len(rest) != length+4 || !bytes.HasSuffix(rest, []byte("\r\n\r\n")) {
+			t.Fatalf("ブロック長がContent-Lengthと一致しません: got %d, want %d+4", len(rest), length)
+		}
+		records = append(records, warcRecord{headers: headers, block: rest[:length]})
+	}
+}
+
+// TestWriteWARC はリダイレクト・レスポンス・描画後のDOMが
+// gzipメンバーごとのWARCレコードとして書き込まれることを検証する。
+func TestWriteWARC(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	exchanges := []*exchange{
+		{
+			url:            "http://example.com/old",
+			method:         "GET",
+			requestHeaders: proto.NetworkHeaders{"User-Agent": gson.New("test")},
+			wallTime:       now,
+			response: &proto.NetworkResponse{
+				Status:  301,
+				Headers: proto.NetworkHeaders{"Location": gson.New("/new")},
+			},
+			redirected: true,
+			finished:   true,
+		},
+		{
+			url:      "http://example.com/new?q=1",
+			method:   "GET",
+			wallTime: now,
+			response: &proto.NetworkResponse{
+				Status:     200,
+				StatusText: "OK",
+				Headers: proto.NetworkHeaders{
+					"Content-Type":     gson.New("text/html"),
+					"Content-Encoding": gson.New("gzip"),
+					"Set-Cookie":       gson.New("a=1\nb=2"),
+				},
+				RemoteIPAddress: "192.0.2.1",
+			},
+			finished:   true,
+			body:       []byte("<html>BODY</html>"),
+			bodyLoaded: true,
+		},
+		{
+			// ボディを取得できなかったレスポンスは書き込まない
+			url:      "http://example.com/missing.png",
+			method:   "GET",
+			wallTime: now,
+			response: &proto.NetworkResponse{Status: 200},
+			finished: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeWARC(&buf, exchanges, "http://example.com/new?q=1", "<html>DOM</html>", now); err != nil {
+		t.Fatalf("writeWARCに失敗: %v", err)
+	}
+	records := readWARCRecords(t, buf.Bytes())
+
+	var types []string
+	for _, r := range records {
+		types = append(types, r.headers.Get("WARC-Type"))
+	}
+	want := "warcinfo,response,request,response,request,resource"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("レコードの種類: got %s, want %s", got, want)
+	}
+
+	redirect := string(records[1].block)
+	assertContains(t, redirect, "HTTP/1.1 301 Moved Permanently\r\n")
+	assertContains(t, redirect, "Location: /new\r\n")
+
+	if got := records[2].headers.Get("WARC-Concurrent-To"); got != records[1].headers.Get("WARC-Record-ID") {
+		t.Errorf("requestレコードのWARC-Concurrent-Toがresponseを指していません: %s", got)
+	}
+	request := string(records[2].block)
+	assertContains(t, request, "GET /old HTTP/1.1\r\nHost: example.com\r\n")
+	assertContains(t, request, "User-Agent: test\r\n")
+
+	response := string(records[3].block)
+	assertContains(t, response, "Set-Cookie: a=1\r\nSet-Cookie: b=2\r\n")
+	assertContains(t, response, "Content-Length: 17\r\n\r\n<html>BODY</html>")
+	if strings.Contains(response, "Content-Encoding") {
+		t.Error("展開済みのボディにContent-Encodingが残っています")
+	}
+	if got := records[3].headers.Get("WARC-IP-Address"); got != "192.0.2.1" {
+		t.Errorf("WARC-IP-Address: got %q", got)
+	}
+	if got := records[3].headers.Get("WARC-Payload-Digest"); got != warcDigest([]byte("<html>BODY</html>")) {
+		t.Errorf("WARC-Payload-Digest: got %q", got)
+	}
+	assertContains(t, string(records[4].block), "GET /new?q=1 HTTP/1.1\r\n")
+
+	dom := records[5]
+	if got := dom.headers.Get("WARC-Target-URI"); got != "urn:rendered-dom:http://example.com/new?q=1" {
+		t.Errorf("resourceレコードのWARC-Target-URI: got %q", got)
+	}
+	if string(dom.block) != "<html>DOM</html>" {
+		t.Errorf("resourceレコードの内容: got %q", dom.block)
+	}
+}